@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/phanorcoll/muxie/internal/capture"
+	"github.com/phanorcoll/muxie/internal/config"
+)
+
+// runCapture writes the history of the panes of the session named in args
+// to the directory given with --out, optionally limited to a window and a
+// pane, and prints where it was written to w.
+func runCapture(w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("capture", flag.ContinueOnError)
+	var filter capture.Filter
+	fs.StringVar(&filter.Window, "window", "", "capture only the window with this index or name")
+	fs.StringVar(&filter.Pane, "pane", "", "capture only the panes with this index or id, e.g. %3")
+	out := fs.String("out", "", "directory to write the capture to")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: muxie capture <session> [--window w] [--pane p] --out dir")
+		fs.PrintDefaults()
+	}
+	name, err := parseSessionArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		fs.Usage()
+		return errors.New("--out is required")
+	}
+
+	dir := config.ExpandHomeDir(*out)
+	m, err := capture.Capture(name, filter, dir)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "captured %d panes of %s to %s\n", m.Panes(), name, dir)
+	return nil
+}
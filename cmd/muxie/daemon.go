@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/phanorcoll/muxie/internal/config"
+	"github.com/phanorcoll/muxie/internal/tmux"
+)
+
+// closedPoll is how often muxie daemon checks whether a session was closed.
+const closedPoll = 2 * time.Second
+
+// runDaemon saves the running sessions when it starts, on an interval and
+// whenever a session is closed, until it is interrupted. Every save is
+// logged to stderr.
+func runDaemon(args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	interval := fs.Duration("interval", cfg.AutosaveInterval(), "time between two snapshots")
+	scrollback := fs.Bool("scrollback", cfg.Autosave.Scrollback, "save the history of every pane too")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: muxie daemon [--interval 5m] [--scrollback]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return errors.New("unexpected arguments")
+	}
+	if *interval <= 0 {
+		return errors.New("the interval must be positive")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	save := func(reason string) {
+		n, err := autosave(cfg, *scrollback)
+		switch {
+		case err != nil:
+			log.Printf("could not save sessions: %v", err)
+		case n > 0:
+			log.Printf("saved %d sessions (%s)", n, reason)
+		}
+	}
+	save("start")
+	names := runningSessions()
+
+	tick := time.NewTicker(*interval)
+	defer tick.Stop()
+	poll := time.NewTicker(closedPoll)
+	defer poll.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tick.C:
+			save("interval")
+		case <-poll.C:
+			current := runningSessions()
+			for name := range names {
+				if !current[name] {
+					save("closed " + name)
+					break
+				}
+			}
+			names = current
+		}
+	}
+}
+
+// runningSessions returns the names of the running sessions, or nil if
+// they cannot be listed.
+func runningSessions() map[string]bool {
+	sessions, err := tmux.GetSessionsList()
+	if err != nil {
+		return nil
+	}
+	names := make(map[string]bool, len(sessions))
+	for _, s := range sessions {
+		names[s.Name] = true
+	}
+	return names
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/phanorcoll/muxie/internal/config"
+	"github.com/phanorcoll/muxie/internal/state"
+	"github.com/phanorcoll/muxie/internal/tmux"
+	"github.com/phanorcoll/muxie/internal/tui"
+)
+
+// Outcomes of a doctor check.
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// check is the outcome of a single doctor check.
+// fix: how to solve a warning or failure, empty when it passed.
+type check struct {
+	name   string
+	status string
+	detail string
+	fix    string
+}
+
+// runDoctor checks the environment muxie runs in and prints the outcome of
+// every check to w, with a fix for each warning or failure.
+// Returns an error if any check failed.
+func runDoctor(w io.Writer) error {
+	var checks []check
+	add := func(c ...check) { checks = append(checks, c...) }
+
+	binary := checkTmuxBinary()
+	add(binary)
+	if binary.status == checkPass {
+		add(checkTmuxVersion())
+		add(checkTmuxFeatures())
+		add(checkTmuxEnv())
+		server := checkTmuxServer()
+		add(server)
+		if server.status == checkPass {
+			add(checkTmuxOptions()...)
+		}
+	}
+	cfg, c := checkConfig()
+	add(c)
+	if cfg != nil {
+		add(checkSessionDirs(cfg)...)
+	}
+	if c.status != checkFail {
+		add(checkIcons(cfg))
+	}
+	add(checkColors())
+	add(checkWritable("state dir", state.Dir))
+	add(checkWritable("config dir", config.Dir))
+
+	failed := 0
+	for _, c := range checks {
+		fmt.Fprintf(w, "%-4s  %-16s  %s\n", c.status, c.name, c.detail)
+		if c.fix != "" {
+			fmt.Fprintf(w, "      %-16s  fix: %s\n", "", c.fix)
+		}
+		if c.status == checkFail {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("checks failed: %d", failed)
+	}
+	return nil
+}
+
+// checkTmuxBinary checks that tmux is in PATH.
+func checkTmuxBinary() check {
+	path, err := exec.LookPath("tmux")
+	if err != nil {
+		return check{"tmux", checkFail, "tmux not found in PATH", "install tmux " + tmux.MinVersion.Raw + " or later"}
+	}
+	return check{"tmux", checkPass, path, ""}
+}
+
+// checkTmuxVersion checks that tmux is recent enough.
+func checkTmuxVersion() check {
+	v, err := tmux.CurrentVersion()
+	if err != nil {
+		return check{"tmux version", checkWarn, err.Error(), "make sure tmux -V prints a version such as \"tmux 3.3a\""}
+	}
+	if err := tmux.CheckVersion(); err != nil {
+		return check{"tmux version", checkFail, err.Error(), "upgrade tmux to " + tmux.MinVersion.Raw + " or later"}
+	}
+	return check{"tmux version", checkPass, v.Raw, ""}
+}
+
+// checkTmuxFeatures lists the features of newer tmux versions that the
+// installed one lacks, which muxie works around or goes without.
+func checkTmuxFeatures() check {
+	var missing []string
+	latest := tmux.Version{}
+	for _, f := range tmux.Features {
+		if tmux.Supports(f) {
+			continue
+		}
+		missing = append(missing, f.Name)
+		if !latest.AtLeast(f.Major, f.Minor) {
+			latest = tmux.Version{Major: f.Major, Minor: f.Minor}
+		}
+	}
+	if len(missing) > 0 {
+		return check{"tmux features", checkWarn, "missing " + strings.Join(missing, ", "),
+			fmt.Sprintf("upgrade tmux to %d.%d or later", latest.Major, latest.Minor)}
+	}
+	return check{"tmux features", checkPass, "all supported", ""}
+}
+
+// checkTmuxEnv checks that muxie runs inside tmux, which switching sessions needs.
+func checkTmuxEnv() check {
+	if os.Getenv("TMUX") == "" {
+		return check{"TMUX", checkWarn, "not set, muxie is not running inside tmux",
+			"run muxie from a tmux session, e.g. with bind-key S display-popup -E muxie"}
+	}
+	return check{"TMUX", checkPass, "running inside tmux", ""}
+}
+
+// checkTmuxServer checks that the tmux server can be reached.
+func checkTmuxServer() check {
+	sessions, err := tmux.GetSessionsList()
+	if err != nil {
+		if tmux.IsNoServer(err) {
+			return check{"tmux server", checkWarn, "no server running", "start tmux"}
+		}
+		return check{"tmux server", checkFail, err.Error(), "check that the tmux server is running and its socket is accessible"}
+	}
+	return check{"tmux server", checkPass, fmt.Sprintf("%d sessions running", len(sessions)), ""}
+}
+
+// checkTmuxOptions reports the base-index and pane-base-index options,
+// which window and pane indices depend on when sessions are started.
+func checkTmuxOptions() []check {
+	var checks []check
+	for _, name := range []string{"base-index", "pane-base-index"} {
+		value, err := tmux.ShowOption(name)
+		if err != nil {
+			checks = append(checks, check{name, checkFail, err.Error(), "check the option in your tmux.conf"})
+			continue
+		}
+		checks = append(checks, check{name, checkPass, value, ""})
+	}
+	return checks
+}
+
+// checkConfig checks that the config file can be loaded and is valid.
+// Returns the config if it could be loaded.
+func checkConfig() (*config.Config, check) {
+	dir, err := config.Dir()
+	if err != nil {
+		return nil, check{"config", checkFail, err.Error(), "set the HOME environment variable"}
+	}
+	file := filepath.Join(dir, "config.yml")
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, check{"config", checkFail, err.Error(), "fix the syntax of " + file}
+	}
+	if err := tui.ValidateConfig(cfg); err != nil {
+		return cfg, check{"config", checkFail, err.Error(), "fix the setting in " + file}
+	}
+	if _, err := os.Stat(file); errors.Is(err, os.ErrNotExist) {
+		return cfg, check{"config", checkWarn, file + " not found, no sessions are defined",
+			"copy " + filepath.Join(dir, "config_example.yaml") + " to " + file}
+	}
+	return cfg, check{"config", checkPass, fmt.Sprintf("%s, %d sessions", file, len(cfg.Sessions)), ""}
+}
+
+// checkSessionDirs checks that the directory of every config session exists.
+func checkSessionDirs(cfg *config.Config) []check {
+	var checks []check
+	for _, s := range cfg.Sessions {
+		if s.Directory == "" {
+			continue
+		}
+		dir := config.ExpandHomeDir(s.Directory)
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			checks = append(checks, check{"session " + s.Name, checkWarn, dir + " is not a directory",
+				"create it or fix the directory of the session in " + s.Source})
+		}
+	}
+	return checks
+}
+
+// checkIcons reports the icon set in use. Whether a Nerd Font is installed
+// cannot be detected, so a sample glyph is printed for the user to check.
+func checkIcons(cfg *config.Config) check {
+	name := tui.IconSet(cfg.Icons)
+	if name == "nerdfont" {
+		return check{"icons", checkPass, "nerdfont (if \"\uf120\" is not a terminal icon, install a Nerd Font or set icons: unicode)", ""}
+	}
+	return check{"icons", checkPass, name, ""}
+}
+
+// checkColors checks that the terminal supports at least 256 colors, which
+// the themes are designed for.
+func checkColors() check {
+	term, colorterm := os.Getenv("TERM"), os.Getenv("COLORTERM")
+	switch {
+	case colorterm == "truecolor" || colorterm == "24bit":
+		return check{"colors", checkPass, "true color", ""}
+	case strings.Contains(term, "256color"):
+		return check{"colors", checkPass, "256 colors (TERM=" + term + ")", ""}
+	case term == "":
+		return check{"colors", checkWarn, "TERM is not set", "run muxie in a terminal"}
+	default:
+		return check{"colors", checkWarn, "limited colors (TERM=" + term + ")",
+			`set default-terminal to "tmux-256color" in your tmux.conf`}
+	}
+}
+
+// checkWritable checks that the directory returned by dir can be created
+// and written to.
+func checkWritable(name string, dir func() (string, error)) check {
+	path, err := dir()
+	if err != nil {
+		return check{name, checkFail, err.Error(), "set the HOME environment variable"}
+	}
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return check{name, checkFail, err.Error(), "make " + filepath.Dir(path) + " writable"}
+	}
+	f, err := os.CreateTemp(path, ".doctor-*")
+	if err != nil {
+		return check{name, checkFail, err.Error(), "make " + path + " writable"}
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return check{name, checkPass, path, ""}
+}
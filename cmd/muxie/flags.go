@@ -0,0 +1,24 @@
+package main
+
+import (
+	"errors"
+	"flag"
+)
+
+// parseSessionArgs parses args with fs, accepting the flags before and
+// after the single session name they must hold. Returns the name, or an
+// error after printing the usage if there is none or more than one.
+func parseSessionArgs(fs *flag.FlagSet, args []string) (string, error) {
+	if err := fs.Parse(args); err != nil {
+		return "", err
+	}
+	name := fs.Arg(0)
+	if err := fs.Parse(fs.Args()[min(1, fs.NArg()):]); err != nil {
+		return "", err
+	}
+	if name == "" || fs.NArg() > 0 {
+		fs.Usage()
+		return "", errors.New("expected a single session name")
+	}
+	return name, nil
+}
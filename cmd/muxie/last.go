@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/phanorcoll/muxie/internal/state"
+	"github.com/phanorcoll/muxie/internal/tmux"
+)
+
+// runLast switches the client to the running session visited most recently
+// from muxie, other than the active one. Running it again toggles back.
+func runLast() error {
+	active, err := tmux.GetActiveSession()
+	if err != nil {
+		return err
+	}
+	sessions, err := tmux.GetSessionsList()
+	if err != nil {
+		return err
+	}
+	running := make(map[string]bool)
+	for _, s := range sessions {
+		running[s.Name] = true
+	}
+
+	history, err := state.LoadHistory()
+	if err != nil {
+		return err
+	}
+	recent := history.Recent()
+	for _, name := range recent {
+		if name == active || !running[name] {
+			continue
+		}
+		if err := tmux.SwitchSession(name); err != nil {
+			return err
+		}
+		// Record the session being left too if it was reached without
+		// muxie, so running the command again switches back to it.
+		if active != "" && recent[0] != active {
+			if err := state.RecordVisit(active); err != nil {
+				return err
+			}
+		}
+		return state.RecordVisit(name)
+	}
+	return errors.New("no previous session in history")
+}
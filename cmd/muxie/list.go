@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/phanorcoll/muxie/internal/tmux"
+)
+
+// runList prints the running tmux sessions as a table to w.
+func runList(w io.Writer) error {
+	sessions, err := tmux.GetSessionsList()
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tWINDOWS\tATTACHED\tCREATED\tACTIVITY\tPATH")
+	for _, s := range sessions {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\n",
+			s.Name, s.NumberWindows, s.Attached, formatTime(s.Created), formatTime(s.Activity), s.Path)
+	}
+	return tw.Flush()
+}
+
+// formatTime formats t for table output, using "-" for the zero time.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.DateTime)
+}
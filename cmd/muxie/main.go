@@ -9,8 +9,14 @@ import (
 	"path/filepath"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/phanorcoll/muxie/internal/capture"
 	"github.com/phanorcoll/muxie/internal/config"
+	"github.com/phanorcoll/muxie/internal/git"
 	applog "github.com/phanorcoll/muxie/internal/log"
+	"github.com/phanorcoll/muxie/internal/project"
+	"github.com/phanorcoll/muxie/internal/snapshot"
+	"github.com/phanorcoll/muxie/internal/state"
+	"github.com/phanorcoll/muxie/internal/tmux"
 	"github.com/phanorcoll/muxie/internal/tui"
 )
 
@@ -19,8 +25,16 @@ var (
 	date    = "unknown"
 )
 
+const (
+	// maxLogSize is the size past which debug.log is rotated.
+	maxLogSize = 5 << 20
+	// logBackups is the number of rotated debug.log files kept.
+	logBackups = 3
+)
+
 func main() {
-	debug := flag.Bool("debug", false, "enable debug logging")
+	debug := flag.Bool("debug", false, "enable debug logging, same as -log-level debug")
+	logLevel := flag.String("log-level", "", "log to ~/.config/muxie/debug.log at the given level: error, warn, info, debug or trace")
 	versionFlag := flag.Bool("version", false, "print version and exit")
 	flag.Parse()
 
@@ -29,8 +43,15 @@ func main() {
 		os.Exit(0)
 	}
 
-	if *debug {
-		log.Println("debug mode enabled")
+	if *logLevel == "" && *debug {
+		*logLevel = "debug"
+	}
+	logger := applog.Discard()
+	if *logLevel != "" {
+		level, err := applog.ParseLevel(*logLevel)
+		if err != nil {
+			log.Fatal(err)
+		}
 		home, err := os.UserHomeDir()
 		if err != nil {
 			log.Fatal(err)
@@ -41,15 +62,66 @@ func main() {
 			log.Fatal(err)
 		}
 
-		logFile := filepath.Join(logDir, "debug.log")
-		f, err := tea.LogToFile(logFile, "debug")
+		f, err := applog.OpenRotating(filepath.Join(logDir, "debug.log"), maxLogSize, logBackups)
 		if err != nil {
 			log.Fatal(err)
 		}
 		defer f.Close()
+		logger = applog.New(f, level)
 	}
+	tmux.SetLogger(logger)
+	git.SetLogger(logger)
+	project.SetLogger(logger)
+	config.SetLogger(logger)
+	state.SetLogger(logger)
+	snapshot.SetLogger(logger)
+	capture.SetLogger(logger)
 
-	logger := applog.New(*debug)
+	switch flag.Arg(0) {
+	case "list":
+		if err := runList(os.Stdout); err != nil {
+			log.Fatalf("could not list sessions: %v", err)
+		}
+		return
+	case "last":
+		if err := runLast(); err != nil {
+			log.Fatalf("could not switch to the last session: %v", err)
+		}
+		return
+	case "doctor":
+		if err := runDoctor(os.Stdout); err != nil {
+			log.Fatalf("doctor: %v", err)
+		}
+		return
+	case "save":
+		if err := runSave(os.Stdout, flag.Args()[1:]); err != nil {
+			log.Fatalf("could not save sessions: %v", err)
+		}
+		return
+	case "daemon":
+		if err := runDaemon(flag.Args()[1:]); err != nil {
+			log.Fatalf("daemon: %v", err)
+		}
+		return
+	case "restore":
+		if err := runRestore(os.Stdout, flag.Args()[1:]); err != nil {
+			log.Fatalf("could not restore sessions: %v", err)
+		}
+		return
+	case "capture":
+		if err := runCapture(os.Stdout, flag.Args()[1:]); err != nil {
+			log.Fatalf("could not capture session: %v", err)
+		}
+		return
+	case "start":
+		if err := runStart(flag.Args()[1:]); err != nil {
+			log.Fatalf("could not start session: %v", err)
+		}
+		return
+	case "":
+	default:
+		log.Fatalf("unknown command %q", flag.Arg(0))
+	}
 
 	config, err := config.Load()
 	if err != nil {
@@ -57,16 +129,19 @@ func main() {
 	}
 
 	if len(config.Sessions) > 0 {
-		logger.Printf("configuration loaded successfully")
+		logger.Info("configuration loaded", "sessions", len(config.Sessions))
 	} else {
-		logger.Printf("no sessions found in config, starting with default")
+		logger.Info("no sessions found in config, starting with default")
 	}
 
-	m := tui.NewModel(config, logger, version)
+	m, err := tui.NewModel(config, logger, version)
+	if err != nil {
+		log.Fatalf("invalid config: %v", err)
+	}
 	p := tea.NewProgram(m, tea.WithAltScreen())
 
 	if _, err := p.Run(); err != nil {
-		log.Printf("error starting: %v", err)
+		logger.Error("could not run the TUI", "err", err)
 		fmt.Printf("Upss, there's been an error: %v", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/phanorcoll/muxie/internal/config"
+	"github.com/phanorcoll/muxie/internal/snapshot"
+	"github.com/phanorcoll/muxie/internal/tmux"
+)
+
+// runSave saves a snapshot of the running sessions, rotating the older
+// ones, and prints how many sessions were saved to w. With --auto it saves
+// silently as muxie daemon does, e.g. from a tmux hook.
+func runSave(w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("save", flag.ContinueOnError)
+	scrollback := fs.Bool("scrollback", false, "save the history of every pane too")
+	auto := fs.Bool("auto", false, "save quietly as muxie daemon does, with the autosave settings of the config")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if *auto {
+		_, err := autosave(cfg, *scrollback || cfg.Autosave.Scrollback)
+		return err
+	}
+
+	snap, err := snapshot.Take(*scrollback)
+	if err != nil {
+		return err
+	}
+	if len(snap.Sessions) == 0 {
+		// Keep the last snapshot rather than replacing it with nothing.
+		return errors.New("no running sessions to save")
+	}
+	if err := snapshot.Save(snap, cfg.Snapshots()); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "saved %d sessions\n", len(snap.Sessions))
+	return nil
+}
+
+// autosave saves a snapshot of the running sessions taken by autosave.
+// Nothing is saved if no session is running, e.g. while the tmux server is
+// stopped, and the last snapshot is replaced rather than rotated if it was
+// autosaved too and the sessions did not change since, so the kept
+// snapshots are not all the same. Returns the number of sessions saved.
+func autosave(cfg *config.Config, scrollback bool) (int, error) {
+	snap, err := snapshot.Take(scrollback)
+	if tmux.IsNoServer(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if len(snap.Sessions) == 0 {
+		return 0, nil
+	}
+	snap.Auto = true
+	last, err := snapshot.Load()
+	if err != nil {
+		return 0, err
+	}
+	if last.Auto && last.SameSessions(snap) {
+		err = snapshot.Replace(snap)
+	} else {
+		err = snapshot.Save(snap, cfg.Snapshots())
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(snap.Sessions), nil
+}
+
+// runRestore recreates the sessions of the last snapshot named in args, or
+// all of them if args is empty, skipping the ones already running. Prints
+// every restored session to w.
+func runRestore(w io.Writer, args []string) error {
+	snap, err := snapshot.Load()
+	if err != nil {
+		return err
+	}
+	if len(snap.Sessions) == 0 {
+		return errors.New("no snapshot saved, run muxie save first")
+	}
+	running := make(map[string]bool)
+	// Restoring usually happens before the server is started, so a failure
+	// to list sessions means none are running.
+	if sessions, err := tmux.GetSessionsList(); err == nil {
+		for _, s := range sessions {
+			running[s.Name] = true
+		}
+	}
+
+	for _, name := range args {
+		if _, ok := snap.Find(name); !ok {
+			return fmt.Errorf("session %q not found in snapshot", name)
+		}
+	}
+	var errs []error
+	for _, s := range snap.Sessions {
+		if len(args) > 0 && !slices.Contains(args, s.Name) || running[s.Name] {
+			continue
+		}
+		if err := snapshot.Restore(s); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		fmt.Fprintf(w, "restored %s\n", s.Name)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/phanorcoll/muxie/internal/config"
+	"github.com/phanorcoll/muxie/internal/state"
+	"github.com/phanorcoll/muxie/internal/tmux"
+	"github.com/phanorcoll/muxie/internal/tui"
+)
+
+// runStart starts the session named in args and switches to it: a config
+// session, a worktree session or a repository of the project roots, as
+// listed by the TUI.
+// If the session is already running, it is switched to as is, unless
+// --reconcile adds the windows and panes missing from it or --restart
+// starts it again from scratch.
+func runStart(args []string) error {
+	fs := flag.NewFlagSet("start", flag.ContinueOnError)
+	reconcile := fs.Bool("reconcile", false, "add the windows and panes missing from a running session")
+	restart := fs.Bool("restart", false, "restart a running session from the config")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: muxie start [--reconcile|--restart] <session>")
+		fs.PrintDefaults()
+	}
+	name, err := parseSessionArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if *reconcile && *restart {
+		return errors.New("--reconcile and --restart cannot be used together")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	s, err := tui.FindSession(cfg, name)
+	if err != nil {
+		return fmt.Errorf("session %q: %w", name, err)
+	}
+
+	// Without a tmux server, no session is running yet.
+	sessions, err := tmux.GetSessionsList()
+	if err != nil && !tmux.IsNoServer(err) {
+		return err
+	}
+	running := false
+	for _, rs := range sessions {
+		if rs.Name == name {
+			running = true
+			break
+		}
+	}
+
+	switch {
+	case !running:
+		err = tmux.StartSession(s, cfg.KeepFailedSessions)
+	case *reconcile:
+		err = tmux.ReconcileSession(s)
+	case *restart:
+		err = tmux.RestartSession(s, cfg.KeepFailedSessions)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Outside of tmux there is no client to switch.
+	if os.Getenv("TMUX") == "" {
+		return nil
+	}
+	if err := tmux.SwitchSession(name); err != nil {
+		return err
+	}
+	return state.RecordVisit(name)
+}
@@ -0,0 +1,153 @@
+// Package capture exports the history of the panes of a tmux session to
+// files, e.g. to attach the output of a terminal to a ticket.
+package capture
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/phanorcoll/muxie/internal/tmux"
+)
+
+// ManifestFile is the name of the file describing a capture, written in
+// its directory next to the captured panes.
+const ManifestFile = "manifest.json"
+
+// Manifest describes the layout of a captured session and the files the
+// history of its panes was written to.
+type Manifest struct {
+	Session  string    `json:"session"`  // Name of the session
+	Captured time.Time `json:"captured"` // Time of the capture
+	Windows  []Window  `json:"windows"`  // Captured windows, ordered by index
+}
+
+// Window is a captured window.
+type Window struct {
+	Index  int    `json:"index"`  // Index of the window within its session
+	Name   string `json:"name"`   // Name of the window
+	Layout string `json:"layout"` // Layout of the panes, as accepted by select-layout
+	Active bool   `json:"active"` // Whether this is the active window of its session
+	Panes  []Pane `json:"panes"`  // Captured panes, ordered by index
+}
+
+// Pane is a captured pane.
+type Pane struct {
+	Index   int    `json:"index"`   // Index of the pane within its window
+	ID      string `json:"id"`      // Unique pane id, e.g. "%3"
+	Command string `json:"command"` // Command running in the pane
+	Path    string `json:"path"`    // Working directory of the pane
+	Active  bool   `json:"active"`  // Whether this is the active pane of its window
+	Lines   int    `json:"lines"`   // Number of lines of history
+	Text    string `json:"text"`    // File holding the history as plain text
+	ANSI    string `json:"ansi"`    // File holding the history with ANSI colors and attributes
+}
+
+// Filter selects the panes of a session to capture. Window is the index or
+// name of a window and Pane the index or id of a pane. Empty fields match
+// every window or pane.
+type Filter struct {
+	Window string
+	Pane   string
+}
+
+// matchWindow reports whether the filter selects window w.
+func (f Filter) matchWindow(w tmux.WindowData) bool {
+	return f.Window == "" || f.Window == strconv.Itoa(w.Index) || f.Window == w.Name
+}
+
+// matchPane reports whether the filter selects pane p.
+func (f Filter) matchPane(p tmux.PaneData) bool {
+	return f.Pane == "" || f.Pane == strconv.Itoa(p.Index) || f.Pane == p.ID
+}
+
+// Capture writes the whole history of the panes of the running session
+// matching filter to dir, created if needed, both as plain text and with
+// ANSI escape sequences, along with a manifest describing the layout.
+// Returns the manifest.
+func Capture(session string, filter Filter, dir string) (Manifest, error) {
+	windows, err := tmux.GetWindowsList()
+	if err != nil {
+		return Manifest{}, err
+	}
+	sessionWindows, ok := windows[session]
+	if !ok {
+		return Manifest{}, fmt.Errorf("session %q is not running", session)
+	}
+
+	m := Manifest{Session: session, Captured: time.Now()}
+	for _, wd := range sessionWindows {
+		if !filter.matchWindow(wd) {
+			continue
+		}
+		w := Window{Index: wd.Index, Name: wd.Name, Layout: wd.Layout, Active: wd.Active}
+		for _, pd := range wd.Panes {
+			if filter.matchPane(pd) {
+				w.Panes = append(w.Panes, Pane{Index: pd.Index, ID: pd.ID, Command: pd.Command, Path: pd.Path, Active: pd.Active})
+			}
+		}
+		if len(w.Panes) > 0 {
+			m.Windows = append(m.Windows, w)
+		}
+	}
+	if len(m.Windows) == 0 {
+		return Manifest{}, errors.New("no pane matches the window and pane given")
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return Manifest{}, err
+	}
+	for i, w := range m.Windows {
+		for j, p := range w.Panes {
+			name := fmt.Sprintf("%d-%d", w.Index, p.Index)
+			text, err := capturePane(p.ID, false, filepath.Join(dir, name+".txt"))
+			if err == nil {
+				_, err = capturePane(p.ID, true, filepath.Join(dir, name+".ansi"))
+			}
+			if err != nil {
+				logger.Error("could not capture pane", "session", session, "pane", p.ID, "err", err)
+				return Manifest{}, fmt.Errorf("capture pane %s of window %q: %w", p.ID, w.Name, err)
+			}
+			logger.Debug("captured pane", "session", session, "pane", p.ID, "file", name)
+			p.Lines = strings.Count(text, "\n")
+			p.Text, p.ANSI = name+".txt", name+".ansi"
+			m.Windows[i].Panes[j] = p
+		}
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return Manifest{}, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestFile), append(data, '\n'), 0o644); err != nil {
+		logger.Error("could not write capture manifest", "dir", dir, "err", err)
+		return Manifest{}, err
+	}
+	logger.Debug("captured session", "session", session, "dir", dir, "panes", m.Panes())
+	return m, nil
+}
+
+// capturePane writes the history of the pane with the given id to file,
+// without the empty lines below the cursor. Returns what was written.
+func capturePane(id string, escapes bool, file string) (string, error) {
+	history, err := tmux.CaptureHistory(id, escapes)
+	if err != nil {
+		return "", err
+	}
+	history = strings.TrimRight(history, "\n") + "\n"
+	return history, os.WriteFile(file, []byte(history), 0o644)
+}
+
+// Panes returns the number of panes captured.
+func (m Manifest) Panes() int {
+	n := 0
+	for _, w := range m.Windows {
+		n += len(w.Panes)
+	}
+	return n
+}
@@ -0,0 +1,16 @@
+package capture
+
+import (
+	"log/slog"
+
+	"github.com/phanorcoll/muxie/internal/log"
+)
+
+// logger is the logger of the package, discarding everything until SetLogger is called.
+var logger = log.Discard()
+
+// SetLogger sets the logger used by the package. At slog.LevelDebug,
+// every captured pane is logged.
+func SetLogger(l *slog.Logger) {
+	logger = l
+}
@@ -5,20 +5,140 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
+// DefaultRefreshInterval is how often the session list is refreshed when
+// refresh_interval is not set in the config file.
+const DefaultRefreshInterval = 2 * time.Second
+
+// Defaults of the autosave section of the config file.
+const (
+	DefaultAutosaveInterval = 5 * time.Minute
+	DefaultSnapshots        = 10
+)
+
 // Config represents the root configuration structure for muxie.
 type Config struct {
 	Sessions []Session `yaml:"sessions"`
+	// RefreshInterval controls how often the TUI polls tmux for session changes.
+	// A negative value disables live updates.
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+	// Compact always renders the TUI without borders, title or preview,
+	// which suits small popups. It is enabled automatically in small terminals.
+	Compact bool `yaml:"compact"`
+	// Keys remaps the key bindings of the TUI, by action name, e.g.
+	// kill: ["ctrl+x", "D"]. Keys must not be bound to more than one action.
+	Keys map[string][]string `yaml:"keys"`
+	// Theme selects the colors of the TUI.
+	Theme Theme `yaml:"theme"`
+	// Icons selects the glyphs of the TUI: auto, nerdfont, unicode or ascii.
+	Icons string `yaml:"icons"`
+	// Display controls how session names are shown in the list.
+	Display Display `yaml:"display"`
+	// Picker lists the directories offered when adding a new session.
+	Picker Picker `yaml:"picker"`
+	// ProjectRoots are directories scanned for git repositories, which are
+	// listed as sessions that can be started.
+	ProjectRoots []string `yaml:"project_roots"`
+	// ProjectDepth is how many directories deep repositories are looked for
+	// under each root.
+	ProjectDepth int `yaml:"project_depth"`
+	// ProjectIgnore are glob patterns of directory names not to scan, e.g. node_modules.
+	ProjectIgnore []string `yaml:"project_ignore"`
+	// ProjectWindows are the windows of repositories without a .muxie.yml file.
+	ProjectWindows []Window `yaml:"project_windows"`
+	// WindowTemplates are windows that can be added to any running session
+	// from the TUI, besides the windows of its own config.
+	WindowTemplates []Window `yaml:"window_templates"`
+	// KeepFailedSessions leaves a session running when starting it fails
+	// halfway, to debug its config, instead of killing it.
+	KeepFailedSessions bool `yaml:"keep_failed_sessions"`
+	// Autosave controls the snapshots of the running sessions taken by
+	// muxie daemon and muxie save --auto.
+	Autosave Autosave `yaml:"autosave"`
+}
+
+// Autosave configures the periodic snapshots of the running sessions.
+// Interval is how often muxie daemon takes one, Keep how many snapshots
+// are kept, and Scrollback whether the history of the panes is saved too.
+type Autosave struct {
+	Interval   time.Duration `yaml:"interval"`
+	Keep       int           `yaml:"keep"`
+	Scrollback bool          `yaml:"scrollback"`
+}
+
+// Picker configures the directories offered by the add dialog.
+// Roots are glob patterns such as "~/code/*"; if Zoxide is true, the
+// directories known to zoxide are offered too.
+type Picker struct {
+	Roots  []string `yaml:"roots"`
+	Zoxide bool     `yaml:"zoxide"`
+}
+
+// Display controls how session names are shown in the list.
+// Name is either as-is (the default) or title. Template, if set, is a
+// text/template with the fields Name, Windows, Attached, Path, Running
+// and FromConfig, and takes precedence over Name.
+type Display struct {
+	Name     string `yaml:"name"`
+	Template string `yaml:"template"`
+}
+
+// Theme selects one of the built-in themes (auto, dark, light or high-contrast)
+// and overrides some of its colors by name, e.g. selected: "#7fd1ae".
+type Theme struct {
+	Name   string            `yaml:"name"`
+	Colors map[string]string `yaml:"colors"`
+}
+
+// Refresh returns the interval at which the session list should be refreshed,
+// falling back to DefaultRefreshInterval when none is configured.
+// Returns 0 if live updates are disabled.
+func (c *Config) Refresh() time.Duration {
+	switch {
+	case c.RefreshInterval < 0:
+		return 0
+	case c.RefreshInterval == 0:
+		return DefaultRefreshInterval
+	}
+	return c.RefreshInterval
+}
+
+// AutosaveInterval returns how often muxie daemon saves the running
+// sessions, falling back to DefaultAutosaveInterval when none is configured.
+func (c *Config) AutosaveInterval() time.Duration {
+	if c.Autosave.Interval <= 0 {
+		return DefaultAutosaveInterval
+	}
+	return c.Autosave.Interval
+}
+
+// Snapshots returns how many snapshots of the running sessions are kept,
+// falling back to DefaultSnapshots when none is configured.
+func (c *Config) Snapshots() int {
+	if c.Autosave.Keep <= 0 {
+		return DefaultSnapshots
+	}
+	return c.Autosave.Keep
 }
 
 // Session defines a session with a name, working directory, and associated windows.
+// Tags are free-form labels used to group sessions in the list.
+// If Worktrees is true, Directory is a git repository and the session is
+// started once per worktree of the repository, rooted in that worktree.
+// Source is the file the session was loaded from.
 type Session struct {
-	Name      string   `yaml:"name"`
-	Directory string   `yaml:"directory"`
-	Windows   []Window `yaml:"windows"`
+	Name      string            `yaml:"name"`
+	Directory string            `yaml:"directory"`
+	Tags      []string          `yaml:"tags"`
+	Worktrees bool              `yaml:"worktrees"`
+	Env       map[string]string `yaml:"env"`
+	Windows   []Window          `yaml:"windows"`
+	Source    string            `yaml:"-"`
 }
 
 // Window represents a window within a session, containing multiple panes split in a layout.
@@ -33,6 +153,7 @@ type Window struct {
 type Pane struct {
 	Command   string `yaml:"command"`
 	Directory string `yaml:"directory"`
+	Size      string `yaml:"size"`
 }
 
 // createExampleConfigFile creates an example configuration file if one does not already exist.
@@ -72,36 +193,93 @@ func createExampleConfigFile(configDir string) error {
 	return nil
 }
 
+// Dir returns the directory holding the configuration file, ~/.config/muxie.
+func Dir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not get user home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "muxie"), nil
+}
+
 // Load reads the muxie configuration file from the user's home directory and returns a Config struct.
 // If the configuration file does not exist, it returns a default Config.
 func Load() (*Config, error) {
-	home, err := os.UserHomeDir()
+	configDir, err := Dir()
 	if err != nil {
-		return nil, fmt.Errorf("could not get user home directory: %w", err)
+		return nil, err
 	}
-
-	configDir := filepath.Join(home, ".config", "muxie")
 	if err := os.MkdirAll(configDir, 0755); err != nil {
 		return nil, fmt.Errorf("could not create config directory: %w", err)
 	}
 
+	var config Config
 	configFile := filepath.Join(configDir, "config.yml")
 	if _, err := os.Stat(configFile); os.IsNotExist(err) {
+		logger.Debug("config file not found, writing an example", "file", configFile)
 		if err := createExampleConfigFile(configDir); err != nil {
+			logger.Error("could not write example config file", "err", err)
 			return nil, err
 		}
-		return &Config{}, nil
+	} else {
+		data, err := os.ReadFile(configFile)
+		if err != nil {
+			logger.Error("could not read config file", "file", configFile, "err", err)
+			return nil, fmt.Errorf("could not read config file: %w", err)
+		}
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			logger.Error("could not parse config file", "file", configFile, "err", err)
+			return nil, fmt.Errorf("could not unmarshal config yaml: %w", err)
+		}
+		setSource(config.Sessions, configFile)
+		logger.Debug("loaded config file", "file", configFile, "sessions", len(config.Sessions))
 	}
 
-	data, err := os.ReadFile(configFile)
+	return &config, nil
+}
+
+// ProjectFile is the name of the file describing the windows of a
+// repository found in the project roots.
+const ProjectFile = ".muxie.yml"
+
+// LoadProject returns the session described by the ProjectFile of the
+// repository in dir, with dir as its directory unless the file sets one.
+// The name of the session is left for the caller to set.
+// Returns false if the repository has no ProjectFile.
+func LoadProject(dir string) (Session, bool, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ProjectFile))
+	if os.IsNotExist(err) {
+		return Session{}, false, nil
+	}
 	if err != nil {
-		return nil, fmt.Errorf("could not read config file: %w", err)
+		return Session{}, false, fmt.Errorf("could not read project file: %w", err)
+	}
+	var s Session
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		logger.Warn("could not parse project file", "dir", dir, "err", err)
+		return Session{}, false, fmt.Errorf("could not unmarshal project file %s: %w", filepath.Join(dir, ProjectFile), err)
 	}
+	if s.Directory == "" {
+		s.Directory = dir
+	}
+	s.Source = filepath.Join(dir, ProjectFile)
+	return s, true, nil
+}
 
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("could not unmarshal config yaml: %w", err)
+// ExpandHomeDir expands a leading ~ in a path to the user's home directory.
+func ExpandHomeDir(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err == nil {
+			return filepath.Join(home, path[1:])
+		}
 	}
+	return path
+}
 
-	return &config, nil
+// setSource records file as the source of the given sessions.
+func setSource(sessions []Session, file string) {
+	for i := range sessions {
+		sessions[i].Source = file
+	}
 }
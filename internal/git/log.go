@@ -0,0 +1,16 @@
+package git
+
+import (
+	"log/slog"
+
+	"github.com/phanorcoll/muxie/internal/log"
+)
+
+// logger is the logger of the package, discarding everything until SetLogger is called.
+var logger = log.Discard()
+
+// SetLogger sets the logger used by the package. At log.LevelTrace,
+// every git command is logged with its duration and exit code.
+func SetLogger(l *slog.Logger) {
+	logger = l
+}
@@ -0,0 +1,99 @@
+// Package git provides utilities for working with git repositories and their worktrees.
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/phanorcoll/muxie/internal/log"
+)
+
+// Worktree represents a working tree of a git repository.
+type Worktree struct {
+	Path     string // Directory of the worktree
+	Head     string // Commit checked out in the worktree
+	Branch   string // Branch checked out, without refs/heads/, empty if detached
+	Detached bool   // Whether HEAD is detached
+	Bare     bool   // Whether this is the bare repository itself
+}
+
+// Worktrees returns the worktrees of the repository in dir, the main one first.
+func Worktrees(dir string) ([]Worktree, error) {
+	output, err := run(dir, "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	return parseWorktrees(output), nil
+}
+
+// parseWorktrees parses the output of git worktree list --porcelain, made of
+// one block of "key value" lines per worktree, separated by empty lines.
+func parseWorktrees(output string) []Worktree {
+	var worktrees []Worktree
+	for block := range strings.SplitSeq(strings.TrimSpace(output), "\n\n") {
+		var w Worktree
+		for line := range strings.SplitSeq(block, "\n") {
+			key, value, _ := strings.Cut(line, " ")
+			switch key {
+			case "worktree":
+				w.Path = value
+			case "HEAD":
+				w.Head = value
+			case "branch":
+				w.Branch = strings.TrimPrefix(value, "refs/heads/")
+			case "detached":
+				w.Detached = true
+			case "bare":
+				w.Bare = true
+			}
+		}
+		if w.Path != "" {
+			worktrees = append(worktrees, w)
+		}
+	}
+	return worktrees
+}
+
+// AddWorktree checks out branch in a new worktree of the repository in dir
+// and returns its path. The worktree is created next to the repository, in
+// a directory named after both. The branch is created from HEAD if it
+// exists neither locally nor on a remote.
+func AddWorktree(dir, branch string) (string, error) {
+	dir = filepath.Clean(dir)
+	path := filepath.Join(filepath.Dir(dir), filepath.Base(dir)+"-"+strings.ReplaceAll(branch, "/", "-"))
+	args := []string{"worktree", "add", path, branch}
+	if !branchExists(dir, branch) {
+		args = []string{"worktree", "add", "-b", branch, path}
+	}
+	if _, err := run(dir, args...); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// branchExists reports whether branch exists locally or on a remote of the
+// repository in dir. git worktree add checks out remote branches as new
+// tracking branches.
+func branchExists(dir, branch string) bool {
+	output, err := run(dir, "for-each-ref", "--format=%(refname)", "refs/heads/"+branch, "refs/remotes/*/"+branch)
+	return err == nil && strings.TrimSpace(output) != ""
+}
+
+// run runs git with the given arguments in dir and returns its output.
+// The error includes what git printed on stderr.
+func run(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	start := time.Now()
+	output, err := cmd.Output()
+	log.Trace(logger, "git", "dir", dir, "args", args, "duration", time.Since(start), "exit", cmd.ProcessState.ExitCode())
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(output), nil
+}
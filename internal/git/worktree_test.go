@@ -0,0 +1,41 @@
+package git
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseWorktrees(t *testing.T) {
+	tests := []struct {
+		output string
+		want   []Worktree
+	}{
+		{output: "", want: nil},
+		{
+			output: "worktree /src/app\nHEAD abc123\nbranch refs/heads/main\n",
+			want:   []Worktree{{Path: "/src/app", Head: "abc123", Branch: "main"}},
+		},
+		{
+			output: "worktree /src/app\nHEAD abc123\nbranch refs/heads/main\n\n" +
+				"worktree /src/app-feat-login\nHEAD def456\nbranch refs/heads/feat/login\n\n" +
+				"worktree /src/app-fix\nHEAD 789abc\ndetached\n",
+			want: []Worktree{
+				{Path: "/src/app", Head: "abc123", Branch: "main"},
+				{Path: "/src/app-feat-login", Head: "def456", Branch: "feat/login"},
+				{Path: "/src/app-fix", Head: "789abc", Detached: true},
+			},
+		},
+		{
+			output: "worktree /src/app.git\nbare\n\nworktree /src/app-main\nHEAD abc123\nbranch refs/heads/main\nlocked\n",
+			want: []Worktree{
+				{Path: "/src/app.git", Bare: true},
+				{Path: "/src/app-main", Head: "abc123", Branch: "main"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		if got := parseWorktrees(tt.output); !slices.Equal(got, tt.want) {
+			t.Errorf("parseWorktrees(%q) = %+v, want %+v", tt.output, got, tt.want)
+		}
+	}
+}
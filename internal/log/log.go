@@ -1,27 +1,58 @@
-// Package log provides a logger that can be disabled.
+// Package log provides the leveled logger shared by muxie's packages and
+// the rotating file it writes to.
 package log
 
-import "log"
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
 
-// Logger is an interface for logging.
-type Logger interface {
-	Printf(format string, v ...interface{})
+// LevelTrace is the most verbose level, below slog.LevelDebug. It records
+// every external command run, e.g. each tmux call with its duration, exit
+// code and stderr.
+const LevelTrace = slog.Level(-8)
+
+// levels maps the names accepted by ParseLevel to their level.
+var levels = map[string]slog.Level{
+	"error": slog.LevelError,
+	"warn":  slog.LevelWarn,
+	"info":  slog.LevelInfo,
+	"debug": slog.LevelDebug,
+	"trace": LevelTrace,
 }
 
-// New returns a new logger.
-func New(debug bool) Logger {
-	if debug {
-		return &logger{}
+// ParseLevel returns the level with the given name: error, warn, info, debug or trace.
+func ParseLevel(name string) (slog.Level, error) {
+	level, ok := levels[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown log level %q, expected error, warn, info, debug or trace", name)
 	}
-	return &noOpLogger{}
+	return level, nil
 }
 
-type logger struct{}
-
-func (l *logger) Printf(format string, v ...interface{}) {
-	log.Printf(format, v...)
+// New returns a logger writing records of the given level and above to w.
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			// slog names levels below debug "DEBUG-4".
+			if a.Key == slog.LevelKey && len(groups) == 0 && a.Value.Any() == LevelTrace {
+				a.Value = slog.StringValue("TRACE")
+			}
+			return a
+		},
+	}))
 }
 
-type noOpLogger struct{}
+// Discard returns a logger dropping every record, used when logging is disabled.
+func Discard() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
 
-func (l *noOpLogger) Printf(format string, v ...interface{}) {}
+// Trace logs a record at LevelTrace.
+func Trace(l *slog.Logger, msg string, args ...any) {
+	l.Log(context.Background(), LevelTrace, msg, args...)
+}
@@ -0,0 +1,104 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		backups int
+		writes  []string
+		want    map[string]string // Contents of the files, by suffix of the log file
+	}{
+		{
+			name:    "under the maximum size",
+			backups: 2,
+			writes:  []string{"one\n", "two\n"},
+			want:    map[string]string{"": "one\ntwo\n"},
+		},
+		{
+			name:    "rotated once",
+			backups: 2,
+			writes:  []string{"one\n", "two\n", "three\n"},
+			want:    map[string]string{"": "three\n", ".1": "one\ntwo\n"},
+		},
+		{
+			name:    "oldest removed",
+			backups: 2,
+			writes:  []string{"one\n", "two\n", "three\n", "four\n", "five\n", "six\n"},
+			want:    map[string]string{"": "six\n", ".1": "four\nfive\n", ".2": "three\n"},
+		},
+		{
+			name:    "write larger than the maximum size",
+			backups: 1,
+			writes:  []string{"a line longer than ten bytes\n", "two\n"},
+			want:    map[string]string{"": "two\n", ".1": "a line longer than ten bytes\n"},
+		},
+		{
+			name:    "no backups",
+			backups: 0,
+			writes:  []string{"one\n", "two\n", "three\n"},
+			want:    map[string]string{"": "three\n"},
+		},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "muxie.log")
+		r, err := OpenRotating(path, 10, tt.backups)
+		if err != nil {
+			t.Fatalf("%s: OpenRotating: %v", tt.name, err)
+		}
+		for _, w := range tt.writes {
+			if _, err := r.Write([]byte(w)); err != nil {
+				t.Fatalf("%s: Write(%q): %v", tt.name, w, err)
+			}
+		}
+		if err := r.Close(); err != nil {
+			t.Fatalf("%s: Close: %v", tt.name, err)
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatalf("%s: ReadDir: %v", tt.name, err)
+		}
+		if len(entries) != len(tt.want) {
+			t.Errorf("%s: %d files, want %d", tt.name, len(entries), len(tt.want))
+		}
+		for suffix, want := range tt.want {
+			data, err := os.ReadFile(path + suffix)
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+				continue
+			}
+			if got := string(data); got != want {
+				t.Errorf("%s: muxie.log%s = %q, want %q", tt.name, suffix, got, want)
+			}
+		}
+	}
+}
+
+func TestRotatingFileReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "muxie.log")
+	if err := os.WriteFile(path, []byte("previous\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// The size of the existing file counts towards the maximum size.
+	r, err := OpenRotating(path, 10, 1)
+	if err != nil {
+		t.Fatalf("OpenRotating: %v", err)
+	}
+	if _, err := r.Write([]byte("next\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	for file, want := range map[string]string{path: "next\n", path + ".1": "previous\n"} {
+		if data, err := os.ReadFile(file); err != nil || string(data) != want {
+			t.Errorf("%s = %q (%v), want %q", filepath.Base(file), data, err, want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Package project finds the project directories sessions can be started in.
+package project
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/phanorcoll/muxie/internal/config"
+	"github.com/phanorcoll/muxie/internal/log"
+	"github.com/phanorcoll/muxie/internal/tmux"
+)
+
+// Dirs returns the directories matching the glob patterns in roots, such as
+// "~/code/*", followed by the directories of zoxide's database if zoxide is
+// true. Directories from zoxide come first, ranked by zoxide's score, and
+// duplicates are removed. A leading ~ in a pattern is expanded to the
+// user's home directory. If zoxide cannot be queried, the directories
+// matching roots are still returned along with the error.
+func Dirs(roots []string, zoxide bool) ([]string, error) {
+	var dirs []string
+	var zerr error
+	seen := make(map[string]bool)
+	add := func(dir string) {
+		if seen[dir] {
+			return
+		}
+		seen[dir] = true
+		dirs = append(dirs, dir)
+	}
+
+	if zoxide {
+		zdirs, err := zoxideDirs()
+		zerr = err
+		for _, dir := range zdirs {
+			add(dir)
+		}
+	}
+
+	for _, root := range roots {
+		matches, err := filepath.Glob(config.ExpandHomeDir(root))
+		if err != nil {
+			return nil, fmt.Errorf("invalid picker root %q: %w", root, err)
+		}
+		for _, match := range matches {
+			if info, err := os.Stat(match); err == nil && info.IsDir() {
+				add(match)
+			}
+		}
+	}
+	return dirs, zerr
+}
+
+// zoxideDirs returns the directories of zoxide's database, highest score first.
+func zoxideDirs() ([]string, error) {
+	start := time.Now()
+	output, err := exec.Command("zoxide", "query", "--list").Output()
+	log.Trace(logger, "zoxide", "duration", time.Since(start), "err", err)
+	if err != nil {
+		return nil, fmt.Errorf("could not query zoxide: %w", err)
+	}
+	var dirs []string
+	for line := range strings.SplitSeq(strings.TrimSpace(string(output)), "\n") {
+		if line != "" {
+			dirs = append(dirs, line)
+		}
+	}
+	return dirs, nil
+}
+
+// SessionName derives a tmux session name from the basename of dir.
+func SessionName(dir string) string {
+	return tmux.SanitizeName(filepath.Base(dir))
+}
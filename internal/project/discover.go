@@ -0,0 +1,73 @@
+package project
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/phanorcoll/muxie/internal/config"
+)
+
+// DefaultDepth is how deep Discover looks for repositories under each root
+// when no depth is configured.
+const DefaultDepth = 3
+
+// Discover returns the git repositories found under roots, looking at most
+// depth directories deep. Directories whose name matches one of the ignore
+// glob patterns, hidden directories and the content of repositories are
+// skipped. Roots that do not exist are ignored.
+func Discover(roots []string, depth int, ignore []string) ([]string, error) {
+	if depth <= 0 {
+		depth = DefaultDepth
+	}
+	var repos []string
+	for _, root := range roots {
+		root = filepath.Clean(config.ExpandHomeDir(root))
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				// Unreadable directories are skipped rather than
+				// aborting the whole scan.
+				if path == root || d != nil && d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if !d.IsDir() {
+				return nil
+			}
+			if path != root && (strings.HasPrefix(d.Name(), ".") || ignored(d.Name(), ignore)) {
+				return fs.SkipDir
+			}
+			if isRepo(path) {
+				repos = append(repos, path)
+				return fs.SkipDir
+			}
+			rel, _ := filepath.Rel(root, path)
+			if rel != "." && strings.Count(rel, string(filepath.Separator))+1 >= depth {
+				return fs.SkipDir
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return repos, nil
+}
+
+// isRepo reports whether dir is the root of a git repository or worktree.
+func isRepo(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// ignored reports whether name matches one of the glob patterns.
+func ignored(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,16 @@
+package project
+
+import (
+	"log/slog"
+
+	"github.com/phanorcoll/muxie/internal/log"
+)
+
+// logger is the logger of the package, discarding everything until SetLogger is called.
+var logger = log.Discard()
+
+// SetLogger sets the logger used by the package. At log.LevelTrace,
+// the zoxide query is logged with its duration.
+func SetLogger(l *slog.Logger) {
+	logger = l
+}
@@ -0,0 +1,136 @@
+package snapshot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/phanorcoll/muxie/internal/state"
+	"github.com/phanorcoll/muxie/internal/tmux"
+)
+
+// scrollbackDir is the directory of the state dir holding the history of
+// restored panes until they print it.
+const scrollbackDir = "scrollback"
+
+// Restore recreates the saved session s, detached, with its windows, pane
+// layouts and working directories. Commands are run again in their panes,
+// after printing the saved history of the pane, if any. If a step fails,
+// the partially restored session is killed.
+func Restore(s Session) error {
+	if len(s.Windows) == 0 {
+		return fmt.Errorf("restore session %s: no windows saved", s.Name)
+	}
+	logger.Debug("restoring session", "session", s.Name, "windows", len(s.Windows))
+	created := false
+	fail := func(step string, err error) error {
+		logger.Error("could not restore session", "session", s.Name, "step", step, "err", err, "rollback", created)
+		if created {
+			if kerr := tmux.KillSession(s.Name); kerr != nil {
+				err = fmt.Errorf("%w (rollback: %v)", err, kerr)
+			}
+		}
+		return fmt.Errorf("restore session %s: %s: %w", s.Name, step, err)
+	}
+
+	var focus string
+	for i, w := range s.Windows {
+		if len(w.Panes) == 0 {
+			w.Panes = []Pane{{Path: s.Path, Active: true}}
+		}
+		var first string
+		var err error
+		if i == 0 {
+			first, err = tmux.NewSessionWindow(s.Name, w.Panes[0].Path, w.Name)
+			created = err == nil
+		} else {
+			first, err = tmux.AddWindow(s.Name, w.Name, w.Panes[0].Path)
+		}
+		if err != nil {
+			return fail(fmt.Sprintf("create window %q", w.Name), err)
+		}
+
+		panes := []string{first}
+		for _, p := range w.Panes[1:] {
+			id, err := tmux.AddPane(panes[len(panes)-1], p.Path)
+			if err != nil {
+				return fail(fmt.Sprintf("split window %q", w.Name), err)
+			}
+			// Share the space evenly until the saved layout is applied,
+			// so there is room for the next pane.
+			if err := tmux.SelectLayout(id, "tiled"); err != nil {
+				return fail(fmt.Sprintf("arrange window %q", w.Name), err)
+			}
+			panes = append(panes, id)
+		}
+		// A saved layout that does not fit the window is not worth failing
+		// for: the panes stay tiled.
+		_ = tmux.SelectLayout(first, w.Layout)
+
+		for j, p := range w.Panes {
+			if err := restorePane(s.Name, i, j, panes[j], p); err != nil {
+				return fail(fmt.Sprintf("restore pane %d of window %q", j, w.Name), err)
+			}
+			if p.Active {
+				if err := tmux.SelectPane(panes[j]); err != nil {
+					return fail(fmt.Sprintf("select pane %d of window %q", j, w.Name), err)
+				}
+				if w.Active {
+					focus = panes[j]
+				}
+			}
+		}
+	}
+	if focus != "" {
+		if err := tmux.FocusPane(focus); err != nil {
+			return fail("select active window", err)
+		}
+	}
+	return nil
+}
+
+// restorePane prints the saved history of pane p, window i, pane j of the
+// session with the given name, then runs its command.
+// target: the id of the restored pane.
+func restorePane(sessionName string, i, j int, target string, p Pane) error {
+	if p.Scrollback != "" {
+		// Worktree sessions have slashes in their names.
+		name := strings.ReplaceAll(sessionName, "/", "_")
+		file, err := writeScrollback(fmt.Sprintf("%s-%d-%d.txt", name, i, j), p.Scrollback)
+		if err != nil {
+			return err
+		}
+		logger.Debug("wrote scrollback", "session", sessionName, "file", file)
+		// The file is removed once printed, so restores leave nothing behind.
+		if err := tmux.SendCommand(target, "clear && cat "+shellQuote(file)+"; rm -f "+shellQuote(file)); err != nil {
+			return err
+		}
+	}
+	if p.Command != "" {
+		return tmux.SendCommand(target, p.Command)
+	}
+	return nil
+}
+
+// writeScrollback writes the history of a pane to the file with the given
+// name in the scrollback directory, for the restored pane to print and
+// remove it.
+// Returns the path of the file.
+func writeScrollback(name, scrollback string) (string, error) {
+	dir, err := state.Dir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, scrollbackDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	file := filepath.Join(dir, name)
+	return file, os.WriteFile(file, []byte(strings.TrimRight(scrollback, "\n")+"\n"), 0o600)
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
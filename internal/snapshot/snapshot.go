@@ -0,0 +1,230 @@
+// Package snapshot saves the layout of running tmux sessions, so they can
+// be restored after the tmux server stopped, e.g. after a reboot.
+package snapshot
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/phanorcoll/muxie/internal/state"
+	"github.com/phanorcoll/muxie/internal/tmux"
+)
+
+// file is the name of the state file holding the last snapshot. Older
+// snapshots are kept as snapshot.1.json, snapshot.2.json and so on.
+const file = "snapshot.json"
+
+// Snapshot is the state of the tmux sessions running at a given time.
+type Snapshot struct {
+	Saved    time.Time `json:"saved"`          // Time the snapshot was taken
+	Auto     bool      `json:"auto,omitempty"` // Whether it was taken by autosave rather than muxie save
+	Sessions []Session `json:"sessions"`       // Sessions running at that time
+}
+
+// Session is a saved tmux session.
+type Session struct {
+	Name    string   `json:"name"`    // Name of the session
+	Path    string   `json:"path"`    // Working directory of the session
+	Windows []Window `json:"windows"` // Windows of the session, ordered by index
+}
+
+// Window is a saved tmux window.
+type Window struct {
+	Name   string `json:"name"`   // Name of the window
+	Layout string `json:"layout"` // Layout of the panes, as accepted by select-layout
+	Active bool   `json:"active"` // Whether this was the active window of its session
+	Panes  []Pane `json:"panes"`  // Panes of the window, ordered by index
+}
+
+// Pane is a saved tmux pane.
+type Pane struct {
+	Path       string `json:"path"`                 // Working directory of the pane
+	Command    string `json:"command,omitempty"`    // Command line running in the pane, empty for a shell
+	Active     bool   `json:"active"`               // Whether this was the active pane of its window
+	Scrollback string `json:"scrollback,omitempty"` // History of the pane, if saved
+}
+
+// Take returns a snapshot of the running sessions. If scrollback is true,
+// the history of every pane is saved too.
+func Take(scrollback bool) (Snapshot, error) {
+	sessions, err := tmux.GetSessionsList()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	windows, err := tmux.GetWindowsList()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	commands := processCommands()
+
+	snap := Snapshot{Saved: time.Now()}
+	for _, sd := range sessions {
+		s := Session{Name: sd.Name, Path: sd.Path}
+		for _, wd := range windows[sd.Name] {
+			w := Window{Name: wd.Name, Layout: wd.Layout, Active: wd.Active}
+			for _, pd := range wd.Panes {
+				p := Pane{Path: pd.Path, Active: pd.Active}
+				if !isShell(pd.Command) {
+					p.Command = commands[pd.PID]
+					if p.Command == "" {
+						p.Command = pd.Command
+					}
+				}
+				if scrollback {
+					// A pane whose history cannot be read is still restored.
+					p.Scrollback, err = tmux.CaptureHistory(pd.ID, false)
+					if err != nil {
+						logger.Warn("could not capture the history of a pane", "pane", pd.ID, "err", err)
+					}
+				}
+				w.Panes = append(w.Panes, p)
+			}
+			s.Windows = append(s.Windows, w)
+		}
+		snap.Sessions = append(snap.Sessions, s)
+	}
+	logger.Debug("took snapshot", "sessions", len(snap.Sessions), "scrollback", scrollback)
+	return snap, nil
+}
+
+// Load returns the last saved snapshot, or an empty one if none was saved.
+func Load() (Snapshot, error) {
+	var s Snapshot
+	err := state.Load(file, &s)
+	return s, err
+}
+
+// List returns the saved snapshots, from the last one to the oldest.
+func List() ([]Snapshot, error) {
+	var snaps []Snapshot
+	for i := 0; ; i++ {
+		var s Snapshot
+		if err := state.Load(rotated(i), &s); err != nil {
+			return snaps, err
+		}
+		if s.Saved.IsZero() {
+			return snaps, nil
+		}
+		snaps = append(snaps, s)
+	}
+}
+
+// Save saves s as the last snapshot, keeping at most keep snapshots: the
+// previous ones are shifted to older names and the oldest are removed.
+func Save(s Snapshot, keep int) error {
+	dir, err := state.Dir()
+	if err != nil {
+		return err
+	}
+	for i := keep; ; i++ {
+		if err := os.Remove(filepath.Join(dir, rotated(i))); err != nil {
+			break
+		}
+		logger.Debug("removed old snapshot", "file", rotated(i))
+	}
+	for i := keep - 1; i >= 1; i-- {
+		// Missing files are expected until enough snapshots were taken.
+		_ = os.Rename(filepath.Join(dir, rotated(i-1)), filepath.Join(dir, rotated(i)))
+	}
+	return state.Save(file, s)
+}
+
+// Replace saves s in place of the last snapshot, without shifting the
+// older ones.
+func Replace(s Snapshot) error {
+	return state.Save(file, s)
+}
+
+// rotated returns the name of the state file holding the snapshot taken
+// i snapshots before the last one.
+func rotated(i int) string {
+	if i == 0 {
+		return file
+	}
+	return fmt.Sprintf("snapshot.%d.json", i)
+}
+
+// SameSessions reports whether s and o saved the same sessions, windows,
+// layouts, directories and commands. Which panes were active and their
+// history are not compared.
+func (s Snapshot) SameSessions(o Snapshot) bool {
+	return reflect.DeepEqual(s.structure(), o.structure())
+}
+
+// structure returns the sessions of s without the active flags and the
+// history of their windows and panes.
+func (s Snapshot) structure() []Session {
+	sessions := make([]Session, len(s.Sessions))
+	for i, ss := range s.Sessions {
+		ss.Windows = slices.Clone(ss.Windows)
+		for j, w := range ss.Windows {
+			w.Active = false
+			w.Panes = slices.Clone(w.Panes)
+			for k := range w.Panes {
+				w.Panes[k].Active = false
+				w.Panes[k].Scrollback = ""
+			}
+			ss.Windows[j] = w
+		}
+		sessions[i] = ss
+	}
+	return sessions
+}
+
+// Find returns the saved session with the given name.
+func (s Snapshot) Find(name string) (Session, bool) {
+	for _, ss := range s.Sessions {
+		if ss.Name == name {
+			return ss, true
+		}
+	}
+	return Session{}, false
+}
+
+// shells are the programs considered an idle pane rather than a command to
+// run again when restoring.
+var shells = map[string]bool{
+	"bash": true, "zsh": true, "fish": true, "sh": true, "dash": true,
+	"ksh": true, "tcsh": true, "csh": true, "nu": true, "elvish": true,
+}
+
+// isShell reports whether command, the name of the program running in a
+// pane, is a shell.
+func isShell(command string) bool {
+	command = strings.TrimPrefix(command, "-") // login shells
+	return command == "" || shells[command] || command == filepath.Base(os.Getenv("SHELL"))
+}
+
+// processCommands returns the command line of the first child of every
+// process, keyed by the parent process id. Panes run a shell, whose child
+// is the command the pane is busy with. Returns nil if ps cannot be run,
+// in which case only the names of the commands are saved.
+func processCommands() map[int]string {
+	out, err := exec.Command("ps", "-ax", "-o", "ppid=,args=").Output()
+	if err != nil {
+		logger.Warn("could not list processes, saving command names only", "err", err)
+		return nil
+	}
+	commands := make(map[int]string)
+	for line := range strings.SplitSeq(string(out), "\n") {
+		ppid, args, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		pid, err := strconv.Atoi(ppid)
+		if err != nil {
+			continue
+		}
+		if _, seen := commands[pid]; !seen {
+			commands[pid] = strings.TrimSpace(args)
+		}
+	}
+	return commands
+}
@@ -0,0 +1,126 @@
+package snapshot
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSaveRotation(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", dir)
+	start := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		keep  int
+		saves int
+		want  []int // Minutes after start of the listed snapshots, last first
+		files []string
+	}{
+		{keep: 3, saves: 1, want: []int{0}, files: []string{"snapshot.json"}},
+		{keep: 3, saves: 2, want: []int{2, 1, 0}, files: []string{"snapshot.json", "snapshot.1.json", "snapshot.2.json"}},
+		{keep: 3, saves: 2, want: []int{4, 3, 2}, files: []string{"snapshot.json", "snapshot.1.json", "snapshot.2.json"}},
+		{keep: 1, saves: 1, want: []int{5}, files: []string{"snapshot.json"}},
+	}
+	n := 0
+	for _, tt := range tests {
+		for range tt.saves {
+			if err := Save(Snapshot{Saved: start.Add(time.Duration(n) * time.Minute)}, tt.keep); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			n++
+		}
+		snaps, err := List()
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		var got []int
+		for _, s := range snaps {
+			got = append(got, int(s.Saved.Sub(start).Minutes()))
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("after %d saves keeping %d: snapshots = %v, want %v", n, tt.keep, got, tt.want)
+		}
+		entries, err := os.ReadDir(filepath.Join(dir, "muxie"))
+		if err != nil {
+			t.Fatalf("ReadDir: %v", err)
+		}
+		if len(entries) != len(tt.files) {
+			t.Errorf("after %d saves keeping %d: %d files, want %q", n, tt.keep, len(entries), tt.files)
+		}
+		for _, f := range tt.files {
+			if _, err := os.Stat(filepath.Join(dir, "muxie", f)); err != nil {
+				t.Errorf("after %d saves keeping %d: %v", n, tt.keep, err)
+			}
+		}
+	}
+}
+
+func TestSameSessions(t *testing.T) {
+	base := Snapshot{Sessions: []Session{{
+		Name: "api",
+		Path: "/srv/api",
+		Windows: []Window{
+			{Name: "code", Layout: "b25d,110x30,0,0,0", Active: true, Panes: []Pane{
+				{Path: "/srv/api", Command: "nvim", Active: true, Scrollback: "$ ls"},
+			}},
+			{Name: "logs", Layout: "b25e,110x30,0,0,1", Panes: []Pane{
+				{Path: "/srv/api", Active: true},
+				{Path: "/srv/api/logs", Command: "tail -f app.log"},
+			}},
+		},
+	}}}
+
+	tests := []struct {
+		name   string
+		change func(*Snapshot)
+		want   bool
+	}{
+		{"unchanged", func(*Snapshot) {}, true},
+		{"saved later", func(s *Snapshot) { s.Saved = time.Now(); s.Auto = true }, true},
+		{"active window", func(s *Snapshot) {
+			s.Sessions[0].Windows[0].Active = false
+			s.Sessions[0].Windows[1].Active = true
+		}, true},
+		{"active pane", func(s *Snapshot) {
+			s.Sessions[0].Windows[1].Panes[0].Active = false
+			s.Sessions[0].Windows[1].Panes[1].Active = true
+		}, true},
+		{"scrollback", func(s *Snapshot) { s.Sessions[0].Windows[0].Panes[0].Scrollback = "$ make" }, true},
+		{"session name", func(s *Snapshot) { s.Sessions[0].Name = "web" }, false},
+		{"window name", func(s *Snapshot) { s.Sessions[0].Windows[1].Name = "tail" }, false},
+		{"layout", func(s *Snapshot) { s.Sessions[0].Windows[1].Layout = "even-horizontal" }, false},
+		{"pane directory", func(s *Snapshot) { s.Sessions[0].Windows[1].Panes[1].Path = "/srv/api" }, false},
+		{"pane command", func(s *Snapshot) { s.Sessions[0].Windows[0].Panes[0].Command = "" }, false},
+		{"pane added", func(s *Snapshot) {
+			s.Sessions[0].Windows[0].Panes = append(s.Sessions[0].Windows[0].Panes, Pane{Path: "/srv/api"})
+		}, false},
+		{"session closed", func(s *Snapshot) { s.Sessions = nil }, false},
+	}
+	for _, tt := range tests {
+		o := clone(base)
+		tt.change(&o)
+		if got := base.SameSessions(o); got != tt.want {
+			t.Errorf("%s: SameSessions = %v, want %v", tt.name, got, tt.want)
+		}
+		if got := o.SameSessions(base); got != tt.want {
+			t.Errorf("%s: reversed SameSessions = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// clone returns a deep copy of s, so tests can change it freely.
+func clone(s Snapshot) Snapshot {
+	s.Sessions = slices.Clone(s.Sessions)
+	for i, ss := range s.Sessions {
+		ss.Windows = slices.Clone(ss.Windows)
+		for j, w := range ss.Windows {
+			w.Panes = slices.Clone(w.Panes)
+			ss.Windows[j] = w
+		}
+		s.Sessions[i] = ss
+	}
+	return s
+}
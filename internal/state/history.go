@@ -0,0 +1,126 @@
+package state
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	// historyFile is the name of the file holding the usage history.
+	historyFile = "history.json"
+	// maxRank is the sum of the ranks above which every rank is aged, so
+	// sessions that are no longer used eventually drop out of the history.
+	maxRank = 1000
+)
+
+// Visit records how often and how recently a session was used.
+type Visit struct {
+	Rank float64   `json:"rank"` // Number of visits, aged over time
+	Last time.Time `json:"last"` // Time of the last visit
+}
+
+// History holds the visits of every session switched to or started from muxie.
+type History struct {
+	Sessions map[string]Visit `json:"sessions"`
+}
+
+// LoadHistory returns the saved usage history, or an empty one if none was saved.
+func LoadHistory() (History, error) {
+	var h History
+	err := Load(historyFile, &h)
+	return h, err
+}
+
+// SaveHistory saves the usage history.
+func SaveHistory(h History) error {
+	return Save(historyFile, h)
+}
+
+// RecordVisit records a visit to the named session in the saved history.
+// The history is loaded right before being updated, so visits recorded by
+// other muxie processes are kept.
+func RecordVisit(name string) error {
+	h, err := LoadHistory()
+	if err != nil {
+		return err
+	}
+	h.Record(name, time.Now())
+	return SaveHistory(h)
+}
+
+// RenameVisits moves the visits of a renamed session to its new name in the
+// saved history.
+func RenameVisits(oldName, newName string) error {
+	h, err := LoadHistory()
+	if err != nil {
+		return err
+	}
+	v, ok := h.Sessions[oldName]
+	if !ok {
+		return nil
+	}
+	delete(h.Sessions, oldName)
+	h.Sessions[newName] = v
+	return SaveHistory(h)
+}
+
+// Record adds a visit to the named session at the given time. Once the
+// ranks add up to more than maxRank, they are all aged and the sessions
+// whose rank drops under 1 are forgotten.
+func (h *History) Record(name string, now time.Time) {
+	if h.Sessions == nil {
+		h.Sessions = make(map[string]Visit)
+	}
+	v := h.Sessions[name]
+	v.Rank++
+	v.Last = now
+	h.Sessions[name] = v
+
+	var total float64
+	for _, v := range h.Sessions {
+		total += v.Rank
+	}
+	if total <= maxRank {
+		return
+	}
+	for n, v := range h.Sessions {
+		v.Rank *= 0.9
+		if v.Rank < 1 {
+			delete(h.Sessions, n)
+			continue
+		}
+		h.Sessions[n] = v
+	}
+}
+
+// Frecency returns the score of the named session at the given time,
+// combining how often and how recently it was visited. Sessions that were
+// never visited score 0.
+func (h History) Frecency(name string, now time.Time) float64 {
+	v, ok := h.Sessions[name]
+	if !ok {
+		return 0
+	}
+	switch age := now.Sub(v.Last); {
+	case age < time.Hour:
+		return v.Rank * 4
+	case age < 24*time.Hour:
+		return v.Rank * 2
+	case age < 7*24*time.Hour:
+		return v.Rank / 2
+	default:
+		return v.Rank / 4
+	}
+}
+
+// Recent returns the names of the visited sessions, most recently visited first.
+func (h History) Recent() []string {
+	names := make([]string, 0, len(h.Sessions))
+	for name := range h.Sessions {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return h.Sessions[names[i]].Last.After(h.Sessions[names[j]].Last)
+	})
+	return names
+}
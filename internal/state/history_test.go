@@ -0,0 +1,63 @@
+package state
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestHistoryRecord(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	var h History
+	h.Record("work", now.Add(-time.Minute))
+	h.Record("work", now)
+	h.Record("notes", now.Add(-time.Hour))
+
+	if got := h.Sessions["work"]; got.Rank != 2 || !got.Last.Equal(now) {
+		t.Errorf("work = %+v, want rank 2 last visited at %v", got, now)
+	}
+	if got := h.Sessions["notes"].Rank; got != 1 {
+		t.Errorf("notes rank = %v, want 1", got)
+	}
+	if got, want := h.Recent(), []string{"work", "notes"}; !slices.Equal(got, want) {
+		t.Errorf("Recent() = %q, want %q", got, want)
+	}
+}
+
+func TestHistoryRecordAging(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	h := History{Sessions: map[string]Visit{
+		"busy":  {Rank: maxRank, Last: now},
+		"stale": {Rank: 1, Last: now},
+	}}
+	h.Record("busy", now)
+
+	if got, want := h.Sessions["busy"].Rank, (maxRank+1)*0.9; got != want {
+		t.Errorf("busy rank = %v, want %v", got, want)
+	}
+	if _, ok := h.Sessions["stale"]; ok {
+		t.Error("stale was kept with a rank under 1")
+	}
+}
+
+func TestHistoryFrecency(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		age  time.Duration
+		want float64
+	}{
+		{age: time.Minute, want: 8},
+		{age: 2 * time.Hour, want: 4},
+		{age: 3 * 24 * time.Hour, want: 1},
+		{age: 30 * 24 * time.Hour, want: 0.5},
+	}
+	for _, tt := range tests {
+		h := History{Sessions: map[string]Visit{"work": {Rank: 2, Last: now.Add(-tt.age)}}}
+		if got := h.Frecency("work", now); got != tt.want {
+			t.Errorf("Frecency after %v = %v, want %v", tt.age, got, tt.want)
+		}
+	}
+	if got := (History{}).Frecency("never", now); got != 0 {
+		t.Errorf("Frecency of a session never visited = %v, want 0", got)
+	}
+}
@@ -0,0 +1,17 @@
+package state
+
+import (
+	"log/slog"
+
+	"github.com/phanorcoll/muxie/internal/log"
+)
+
+// logger is the logger of the package, discarding everything until SetLogger is called.
+var logger = log.Discard()
+
+// SetLogger sets the logger used by the package. State files that cannot
+// be read or written are logged as errors, and every saved file at
+// slog.LevelDebug.
+func SetLogger(l *slog.Logger) {
+	logger = l
+}
@@ -0,0 +1,26 @@
+package state
+
+import "time"
+
+// projectsFile is the name of the file caching the discovered repositories.
+const projectsFile = "projects.json"
+
+// Projects caches the git repositories found in the project roots, so they
+// can be listed without scanning the disk every time muxie opens.
+type Projects struct {
+	Key     string    `json:"key"`     // Settings the roots were scanned with
+	Scanned time.Time `json:"scanned"` // Time of the scan
+	Dirs    []string  `json:"dirs"`    // Repositories found
+}
+
+// LoadProjects returns the cached repositories, or zero values if none were cached.
+func LoadProjects() (Projects, error) {
+	var p Projects
+	err := Load(projectsFile, &p)
+	return p, err
+}
+
+// SaveProjects caches the discovered repositories.
+func SaveProjects(p Projects) error {
+	return Save(projectsFile, p)
+}
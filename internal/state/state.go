@@ -0,0 +1,88 @@
+// Package state persists muxie's state between runs, such as UI preferences.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Dir returns the directory where muxie keeps its state, following the XDG
+// base directory specification: $XDG_STATE_HOME/muxie, or ~/.local/state/muxie.
+func Dir() (string, error) {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "muxie"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not get user home directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "state", "muxie"), nil
+}
+
+// Load decodes the JSON state file with the given name into v.
+// v is left untouched if the file does not exist.
+func Load(name string, v any) error {
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		logger.Error("could not read state file", "file", name, "err", err)
+		return fmt.Errorf("could not read state file %s: %w", name, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		logger.Error("could not decode state file", "file", name, "err", err)
+		return fmt.Errorf("could not decode state file %s: %w", name, err)
+	}
+	return nil
+}
+
+// Save encodes v as JSON into the state file with the given name.
+// The file is replaced atomically so concurrent muxie processes never
+// read a partially written file.
+func Save(name string, v any) error {
+	if err := save(name, v); err != nil {
+		logger.Error("could not save state file", "file", name, "err", err)
+		return err
+	}
+	logger.Debug("saved state file", "file", name)
+	return nil
+}
+
+// save encodes v into the state file with the given name, as described by Save.
+func save(name string, v any) error {
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("could not create state directory: %w", err)
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode state file %s: %w", name, err)
+	}
+	tmp, err := os.CreateTemp(dir, name+".*")
+	if err != nil {
+		return fmt.Errorf("could not write state file %s: %w", name, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("could not write state file %s: %w", name, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("could not write state file %s: %w", name, err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, name)); err != nil {
+		return fmt.Errorf("could not write state file %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package state
+
+// uiFile is the name of the file holding the UI preferences.
+const uiFile = "ui.json"
+
+// UI holds the preferences of the TUI that persist between runs.
+type UI struct {
+	Sort  string `json:"sort"`  // Sort mode of the session list
+	Group string `json:"group"` // Grouping mode of the session list
+}
+
+// LoadUI returns the saved UI preferences, or zero values if none were saved.
+func LoadUI() (UI, error) {
+	var ui UI
+	err := Load(uiFile, &ui)
+	return ui, err
+}
+
+// SaveUI saves the UI preferences.
+func SaveUI(ui UI) error {
+	return Save(uiFile, ui)
+}
@@ -0,0 +1,76 @@
+// Package tmux provides utilities for interacting with and managing tmux sessions.
+package tmux
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/phanorcoll/muxie/internal/log"
+)
+
+// Error is returned when a tmux command fails. It carries the arguments
+// tmux was run with and what it printed on stderr, which usually explains
+// the failure, e.g. "duplicate session: work".
+type Error struct {
+	Args     []string // Arguments tmux was run with
+	ExitCode int      // Exit code of tmux, -1 if it could not be run
+	Stderr   string   // What tmux printed on stderr, trimmed
+	Err      error    // Underlying error from os/exec
+}
+
+func (e *Error) Error() string {
+	command := "tmux"
+	if len(e.Args) > 0 {
+		command += " " + e.Args[0]
+	}
+	if e.Stderr != "" {
+		return fmt.Sprintf("%s: %s", command, e.Stderr)
+	}
+	return fmt.Sprintf("%s: %v", command, e.Err)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// run runs tmux with the given arguments, discarding its output.
+// Returns an *Error if tmux fails.
+func run(args ...string) error {
+	_, err := output(args...)
+	return err
+}
+
+// output runs tmux with the given arguments and returns what it printed on stdout.
+// Returns an *Error if tmux fails.
+func output(args ...string) (string, error) {
+	cmd := exec.Command("tmux", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	start := time.Now()
+	out, err := cmd.Output()
+	e := &Error{Args: args, Stderr: strings.TrimSpace(stderr.String()), Err: err}
+	if err != nil {
+		e.ExitCode = -1
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			e.ExitCode = exitErr.ExitCode()
+		}
+	}
+	log.Trace(logger, "tmux", "args", args, "duration", time.Since(start), "exit", e.ExitCode, "stderr", e.Stderr)
+	if err != nil {
+		return "", e
+	}
+	return string(out), nil
+}
+
+// IsNoServer reports whether err is a tmux failure caused by the tmux
+// server not running.
+func IsNoServer(err error) bool {
+	var e *Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	return strings.Contains(e.Stderr, "no server running") || strings.HasPrefix(e.Stderr, "error connecting to")
+}
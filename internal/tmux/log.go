@@ -0,0 +1,16 @@
+package tmux
+
+import (
+	"log/slog"
+
+	"github.com/phanorcoll/muxie/internal/log"
+)
+
+// logger is the logger of the package, discarding everything until SetLogger is called.
+var logger = log.Discard()
+
+// SetLogger sets the logger used by the package. At log.LevelTrace, every
+// tmux command is logged with its duration, exit code and stderr.
+func SetLogger(l *slog.Logger) {
+	logger = l
+}
@@ -2,60 +2,215 @@
 package tmux
 
 import (
+	"errors"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
 	"github.com/phanorcoll/muxie/internal/config"
 )
 
-// StartSession creates a new tmux session with the given sessionName and starting directory.
-// It then creates the specified windows and panes, running the configured commands in each pane.
-// Returns an error if any tmux operation fails.
-func StartSession(sessionName, sessionDirectory string, windows []config.Window) error {
-	if err := CreateSession(sessionName, sessionDirectory); err != nil {
-		return fmt.Errorf("failed to create new session '%s': %w", sessionName, err)
+// StartError is returned by StartSession when a step of starting a session fails.
+type StartError struct {
+	Session string // Name of the session being started
+	Step    string // Step that failed, e.g. `split window "editor"`
+	Err     error  // Error of the failed step, joined with the rollback error if any
+	Kept    bool   // Whether the partially started session was left running
+}
+
+// Error returns the failed step and its error.
+func (e *StartError) Error() string {
+	msg := fmt.Sprintf("start session %s: %s: %v", e.Session, e.Step, e.Err)
+	if e.Kept {
+		msg += " (session kept)"
 	}
-	sessionDirectory = expandHomeDir(sessionDirectory)
+	return msg
+}
+
+// Unwrap returns the error of the failed step.
+func (e *StartError) Unwrap() error {
+	return e.Err
+}
 
-	// Get the base pane index, which we will use several times
-	// while starting a predefined session
-	basePaneIndex, err := GetPaneBaseIndex()
+// StartSession creates a new detached tmux session from s, in its directory
+// and with its environment variables.
+// It then creates the specified windows and panes, running the configured commands in each pane.
+// Callers switch to the session with SwitchSession once it is started.
+// If a step fails after the session was created, the session is killed,
+// unless keep is true, so it can be started again. Returns a *StartError
+// reporting the failed step.
+func StartSession(s config.Session, keep bool) error {
+	sessionName := s.Name
+	startWindow, err := newSession(sessionName, s.Directory, s.Env)
 	if err != nil {
-		return err
+		// The session may be one that was already running: leave it alone.
+		return &StartError{Session: sessionName, Step: "create session", Err: err}
+	}
+	// Without new-session -e, the windows created next still inherit the
+	// environment of the session.
+	step := "set environment"
+	err = setEnvironment(sessionName, s.Env, !Supports(SessionEnv))
+	if err == nil {
+		step, err = buildSession(sessionName, config.ExpandHomeDir(s.Directory), s.Windows, startWindow)
+	}
+	if err != nil {
+		logger.Warn("could not start session", "session", sessionName, "step", step, "err", err, "kept", keep)
+		serr := &StartError{Session: sessionName, Step: step, Err: err, Kept: keep}
+		if !keep {
+			if kerr := KillSession(sessionName); kerr != nil {
+				serr.Err = errors.Join(err, fmt.Errorf("rollback: %w", kerr))
+			}
+		}
+		return serr
 	}
+	return nil
+}
 
+// buildSession creates the windows and panes of a newly created session,
+// then kills startWindow, the window tmux created with the session.
+// Returns the step that failed and its error.
+func buildSession(sessionName, sessionDirectory string, windows []config.Window, startWindow string) (string, error) {
 	for _, w := range windows {
-		if err := NewWindow(sessionName, w.Name, sessionDirectory); err != nil {
-			return fmt.Errorf("failed to create window '%s' in session '%s': %w", w.Name, sessionName, err)
+		pane, err := newWindow(sessionName, w.Name, sessionDirectory)
+		if err != nil {
+			return fmt.Sprintf("create window %q", w.Name), err
 		}
+		if step, err := buildPanes(pane, sessionDirectory, w, 0); err != nil {
+			return step, err
+		}
+	}
 
-		windowDirectory := sessionDirectory
-		if w.Directory != "" {
-			windowDirectory = expandHomeDir(w.Directory)
+	// After starting the predefined session, delete its starting window
+	// as it is not part of the user's config file
+	if len(windows) > 0 {
+		if err := run("kill-window", "-t", startWindow); err != nil {
+			return "kill start window", err
 		}
+	}
+	return "", nil
+}
 
-		for j, p := range w.Panes {
-			if j > 0 {
-				if err := SplitWindow(sessionName, w.Name, w.Layout); err != nil {
-					return fmt.Errorf("failed to split window '%s' in session '%s': %w", w.Name, sessionName, err)
-				}
-			}
+// buildPanes creates the panes of window w from the one with index from,
+// running their commands. The first pane of a window exists once the window
+// is created; every other pane is split from the previous one.
+// last: the id of the last pane of the running window, e.g. "%3". Panes
+// are targeted by id, as splitting a pane renumbers the panes after it.
+// Returns the step that failed and its error.
+func buildPanes(last, sessionDirectory string, w config.Window, from int) (string, error) {
+	windowDirectory := sessionDirectory
+	if w.Directory != "" {
+		windowDirectory = config.ExpandHomeDir(w.Directory)
+	}
 
-			paneDirectory := windowDirectory 
-			if p.Directory != "" {
-				paneDirectory = expandHomeDir(p.Directory)
+	for j, p := range w.Panes {
+		if j < from {
+			continue
+		}
+		if j > 0 {
+			pane, err := splitPane(last, w.Layout, p.Size)
+			if err != nil {
+				return fmt.Sprintf("split window %q", w.Name), err
 			}
+			last = pane
+		}
 
-			if err := SendKeys(sessionName, w.Name, basePaneIndex + j, fmt.Sprintf("cd %s && clear && %s", paneDirectory, p.Command)); err != nil {
-				return fmt.Errorf("failed to send keys to pane %d in window '%s' of session '%s': %w", j, w.Name, sessionName, err)
-			}
+		paneDirectory := windowDirectory
+		if p.Directory != "" {
+			paneDirectory = config.ExpandHomeDir(p.Directory)
+		}
+
+		if err := sendKeys(last, fmt.Sprintf("cd %s && clear && %s", paneDirectory, p.Command)); err != nil {
+			return fmt.Sprintf("send keys to pane %d of window %q", j, w.Name), err
 		}
 	}
+	return "", nil
+}
 
-	// After starting the predefined session, delete its starting window
-	// as it is not part of the user's config file
-	err = KillWindow(sessionName, basePaneIndex)
+// ReconcileSession adds the windows and panes of s that are missing from the
+// running session with the same name, and sets its environment variables
+// for the windows created from then on. Windows are matched by name;
+// existing windows and panes are left untouched. Returns a *StartError
+// reporting the failed step, with Kept set as the session is never killed.
+func ReconcileSession(s config.Session) error {
+	sessionName := s.Name
+	fail := func(step string, err error) error {
+		return &StartError{Session: sessionName, Step: step, Err: err, Kept: true}
+	}
+	sessionDirectory := config.ExpandHomeDir(s.Directory)
+	if err := setEnvironment(sessionName, s.Env, true); err != nil {
+		return fail("set environment", err)
+	}
+	running, err := GetWindowsList()
 	if err != nil {
+		return fail("list windows", err)
+	}
+	existing := make(map[string]WindowData)
+	for _, w := range running[sessionName] {
+		existing[w.Name] = w
+	}
+
+	for _, w := range s.Windows {
+		// Missing panes are split from the last one, after the existing panes.
+		last, n := "", 0
+		if rw, ok := existing[w.Name]; ok && len(rw.Panes) > 0 {
+			last, n = rw.Panes[len(rw.Panes)-1].ID, len(rw.Panes)
+		} else {
+			last, err = newWindow(sessionName, w.Name, sessionDirectory)
+			if err != nil {
+				return fail(fmt.Sprintf("create window %q", w.Name), err)
+			}
+		}
+		if step, err := buildPanes(last, sessionDirectory, w, n); err != nil {
+			return fail(step, err)
+		}
+	}
+	return nil
+}
+
+// RestartSession replaces the running session with the same name as s by a
+// new one started from s. The new session is started while the
+// old one is still running under another name, so if starting fails the old
+// session is restored. Clients attached to the old session are switched to
+// the new one before it is killed.
+func RestartSession(s config.Session, keep bool) error {
+	sessionName := s.Name
+	oldName := sessionName + " (restarting)"
+	if err := RenameSession(sessionName, oldName); err != nil {
+		return &StartError{Session: sessionName, Step: "rename running session", Err: err, Kept: true}
+	}
+	if err := StartSession(s, keep); err != nil {
+		if rerr := RenameSession(oldName, sessionName); rerr != nil {
+			return errors.Join(err, fmt.Errorf("restore running session: %w", rerr))
+		}
 		return err
 	}
+	clients, err := output("list-clients", "-t", oldName, "-F", "#{client_name}")
+	if err != nil {
+		return &StartError{Session: sessionName, Step: "list clients", Err: err, Kept: true}
+	}
+	for client := range strings.FieldsSeq(clients) {
+		if err := run("switch-client", "-c", client, "-t", sessionName); err != nil {
+			return &StartError{Session: sessionName, Step: "switch client " + client, Err: err, Kept: true}
+		}
+	}
+	if err := KillSession(oldName); err != nil {
+		return &StartError{Session: sessionName, Step: "kill running session", Err: err, Kept: true}
+	}
+	return nil
+}
 
+// setEnvironment sets the environment variables of the session with the
+// given name, which windows and panes created from then on inherit. Does
+// nothing unless set is true, as new-session -e already set them.
+func setEnvironment(sessionName string, env map[string]string, set bool) error {
+	if !set {
+		return nil
+	}
+	for _, name := range slices.Sorted(maps.Keys(env)) {
+		if err := run("set-environment", "-t", sessionName, name, env[name]); err != nil {
+			return err
+		}
+	}
 	return nil
 }
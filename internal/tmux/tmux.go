@@ -3,87 +3,244 @@ package tmux
 
 import (
 	"fmt"
-	"log"
-	"os"
-	"os/exec"
-	"path/filepath"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/phanorcoll/muxie/internal/config"
 )
 
 // SessionData represents information about a tmux session,
-// including its name and the number of windows it contains.
+// including its name, the number of windows it contains and its activity.
 type SessionData struct {
-	Name          string // Name of the tmux session
-	NumberWindows int    // Number of windows in the session
+	Name          string    // Name of the tmux session
+	NumberWindows int       // Number of windows in the session
+	Attached      int       // Number of clients attached to the session
+	Created       time.Time // Time the session was created
+	Activity      time.Time // Time of the last activity in the session
+	LastAttached  time.Time // Time a client was last attached, zero if never attached
+	Path          string    // Working directory of the session
 }
 
-// GetSessionsList retrieves a list of all tmux sessions along with their window counts.
+// fieldSeparator separates the fields of the formats used to list sessions
+// and panes. Some tmux versions print control characters such as tabs as
+// "_", so a printable separator is used instead.
+const fieldSeparator = "|:|"
+
+// sessionFormat is the list-sessions format used by GetSessionsList.
+// Fields are parsed by parseSessionLine. The path comes last as it is the
+// only field that could contain the separator.
+var sessionFormat = strings.Join([]string{
+	"#{session_name}",
+	"#{session_windows}",
+	"#{session_attached}",
+	"#{session_created}",
+	"#{session_activity}",
+	"#{session_last_attached}",
+	"#{session_path}",
+}, fieldSeparator)
+
+// GetSessionsList retrieves a list of all tmux sessions along with their window counts,
+// attached clients, timestamps and paths using a single list-sessions call.
 // Returns a slice of SessionData and an error if the command fails.
 func GetSessionsList() ([]SessionData, error) {
-	cmd := exec.Command("tmux", "list-sessions", "-F", "#S")
-	output, err := cmd.Output()
+	out, err := output("list-sessions", "-F", sessionFormat)
 	if err != nil {
-		log.Println("Error listing tmux sessions:", err)
+		logger.Error("could not list sessions", "err", err)
 		return nil, err
 	}
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
 	var sessions []SessionData
-	for _, name := range lines {
-		if name != "" {
-			winCmd := exec.Command("tmux", "list-windows", "-t", name, "-F", "#W")
-			winOutput, winErr := winCmd.Output()
-			numWindows := 0
-			if winErr == nil {
-				winLines := strings.SplitSeq(strings.TrimSpace(string(winOutput)), "\n")
-				for w := range winLines {
-					if w != "" {
-						numWindows++
-					}
-				}
-			} else {
-				log.Println("Error listing windows for session", name, ":", winErr)
-			}
-			sessions = append(sessions, SessionData{Name: name, NumberWindows: numWindows})
+	for line := range strings.SplitSeq(strings.TrimSpace(out), "\n") {
+		if line == "" {
+			continue
 		}
+		s, err := parseSessionLine(line)
+		if err != nil {
+			logger.Warn("skipping session", "err", err)
+			continue
+		}
+		sessions = append(sessions, s)
 	}
 	return sessions, nil
 }
 
+// parseSessionLine parses a single line of list-sessions output produced with sessionFormat.
+func parseSessionLine(line string) (SessionData, error) {
+	fields := strings.SplitN(line, fieldSeparator, 7)
+	if len(fields) != 7 {
+		return SessionData{}, fmt.Errorf("unexpected session format %q", line)
+	}
+	windows, _ := strconv.Atoi(fields[1])
+	attached, _ := strconv.Atoi(fields[2])
+	return SessionData{
+		Name:          fields[0],
+		NumberWindows: windows,
+		Attached:      attached,
+		Created:       parseUnixTime(fields[3]),
+		Activity:      parseUnixTime(fields[4]),
+		LastAttached:  parseUnixTime(fields[5]),
+		Path:          fields[6],
+	}, nil
+}
+
+// parseUnixTime converts a tmux timestamp (seconds since epoch) into a time.Time.
+// Returns the zero time if the value cannot be parsed.
+func parseUnixTime(value string) time.Time {
+	sec, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+// WindowData represents a window of a running tmux session and its panes.
+type WindowData struct {
+	Index  int        // Index of the window within its session
+	Name   string     // Name of the window
+	Active bool       // Whether this is the active window of its session
+	Layout string     // Layout of the panes, as accepted by select-layout
+	Panes  []PaneData // Panes of the window, ordered by index
+}
+
+// PaneData represents a single pane of a tmux window.
+type PaneData struct {
+	Index   int    // Index of the pane within its window
+	ID      string // Unique pane id, e.g. "%3"
+	Command string // Command currently running in the pane
+	Path    string // Current working directory of the pane
+	Active  bool   // Whether this is the active pane of its window
+	PID     int    // Process id of the program the pane was started with, usually a shell
+}
+
+// ActivePane returns the active pane of the window, or the first pane if none is marked active.
+func (w WindowData) ActivePane() PaneData {
+	for _, p := range w.Panes {
+		if p.Active {
+			return p
+		}
+	}
+	if len(w.Panes) > 0 {
+		return w.Panes[0]
+	}
+	return PaneData{}
+}
+
+// paneFormat is the list-panes format used by GetWindowsList. The path
+// comes last as in sessionFormat.
+var paneFormat = strings.Join([]string{
+	"#{session_name}",
+	"#{window_index}",
+	"#{window_name}",
+	"#{window_active}",
+	"#{pane_index}",
+	"#{pane_id}",
+	"#{pane_current_command}",
+	"#{pane_active}",
+	"#{window_layout}",
+	"#{pane_pid}",
+	"#{pane_current_path}",
+}, fieldSeparator)
+
+// GetWindowsList retrieves the windows and panes of every running tmux session
+// using a single list-panes call. The result is keyed by session name, with
+// windows ordered by index.
+func GetWindowsList() (map[string][]WindowData, error) {
+	out, err := output("list-panes", "-a", "-F", paneFormat)
+	if err != nil {
+		logger.Error("could not list panes", "err", err)
+		return nil, err
+	}
+	windows := make(map[string][]WindowData)
+	for line := range strings.SplitSeq(strings.TrimSpace(out), "\n") {
+		fields := strings.SplitN(line, fieldSeparator, 11)
+		if len(fields) != 11 {
+			continue
+		}
+		sessionName := fields[0]
+		windowIndex, _ := strconv.Atoi(fields[1])
+		paneIndex, _ := strconv.Atoi(fields[4])
+		pid, _ := strconv.Atoi(fields[9])
+		pane := PaneData{
+			Index:   paneIndex,
+			ID:      fields[5],
+			Command: fields[6],
+			Path:    fields[10],
+			Active:  fields[7] == "1",
+			PID:     pid,
+		}
+
+		// list-panes -a lists panes grouped by session and window,
+		// so a new window always starts after the last one seen.
+		sw := windows[sessionName]
+		if len(sw) == 0 || sw[len(sw)-1].Index != windowIndex {
+			sw = append(sw, WindowData{
+				Index:  windowIndex,
+				Name:   fields[2],
+				Active: fields[3] == "1",
+				Layout: fields[8],
+			})
+		}
+		last := &sw[len(sw)-1]
+		last.Panes = append(last.Panes, pane)
+		windows[sessionName] = sw
+	}
+	return windows, nil
+}
+
 // RenameSession renames an existing tmux session from oldName to newName.
 // Returns an error if the command fails.
 func RenameSession(oldName, newName string) error {
-	cmd := exec.Command("tmux", "rename-session", "-t", oldName, newName)
-	if err := cmd.Run(); err != nil {
-		return err
-	}
-	return nil
+	return run("rename-session", "-t", oldName, newName)
 }
 
 // CreateSession creates a new tmux session with the given name and starting directory.
 // Switches to the new session after creation.
 // Returns an error if the command fails.
 func CreateSession(name string, dirname string) error {
-	dirname = expandHomeDir(dirname)
-	cmd := exec.Command("tmux", "new-session", "-d", "-s", name, "-c", dirname, "-n main")
-	if err := cmd.Run(); err != nil {
-		log.Println("Error creating session:", err)
+	if err := NewSession(name, dirname); err != nil {
 		return err
 	}
-	switchCmd := exec.Command("tmux", "switch-client", "-t", name)
-	if err := switchCmd.Run(); err != nil {
-		log.Println("Error switching to new session:", err)
+	if err := run("switch-client", "-t", name); err != nil {
+		logger.Error("could not switch to new session", "session", name, "err", err)
 		return err
 	}
 	return nil
 }
 
+// NewSession creates a new detached tmux session with the given name and
+// starting directory, without switching to it.
+// Returns an error if the command fails.
+func NewSession(name string, dirname string) error {
+	_, err := newSession(name, dirname, nil)
+	return err
+}
+
+// newSession creates a new detached tmux session like NewSession, with the
+// given environment variables if tmux supports new-session -e.
+// Returns the id of the window created with the session, e.g. "@4".
+func newSession(name string, dirname string, env map[string]string) (string, error) {
+	dirname = config.ExpandHomeDir(dirname)
+	args := []string{"new-session", "-d", "-P", "-F", "#{window_id}", "-s", name, "-c", dirname, "-n", "main"}
+	if Supports(SessionEnv) {
+		for _, k := range slices.Sorted(maps.Keys(env)) {
+			args = append(args, "-e", k+"="+env[k])
+		}
+	}
+	out, err := output(args...)
+	if err != nil {
+		logger.Error("could not create session", "session", name, "err", err)
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
 // KillSession kills the tmux session with the given name.
 // Returns an error if the command fails.
 func KillSession(name string) error {
-	cmd := exec.Command("tmux", "kill-session", "-t", name)
-	if err := cmd.Run(); err != nil {
-		log.Println("Error killing session:", err)
+	if err := run("kill-session", "-t", name); err != nil {
+		logger.Error("could not kill session", "session", name, "err", err)
 		return err
 	}
 	return nil
@@ -92,9 +249,8 @@ func KillSession(name string) error {
 // KillWindow kills the tmux window with the given session name and window index.
 // Returns an error if the command fails.
 func KillWindow(sessionName string, index int) error {
-	cmd := exec.Command("tmux", "kill-window", "-t", fmt.Sprintf("%s:%d", sessionName, index))
-	if err := cmd.Run(); err != nil {
-		log.Println("Error killing session:", err)
+	if err := run("kill-window", "-t", fmt.Sprintf("%s:%d", sessionName, index)); err != nil {
+		logger.Error("could not kill window", "session", sessionName, "window", index, "err", err)
 		return err
 	}
 	return nil
@@ -103,13 +259,12 @@ func KillWindow(sessionName string, index int) error {
 // GetActiveSession returns the name of the currently active tmux session.
 // Returns an empty string if there is no active session.
 func GetActiveSession() (string, error) {
-	cmd := exec.Command("tmux", "display-message", "-p", "#S")
-	output, err := cmd.Output()
+	out, err := output("display-message", "-p", "#S")
 	if err != nil {
-		log.Println("Error getting active session:", err)
+		logger.Error("could not get active session", "err", err)
 		return "", err
 	}
-	activeSession := strings.TrimSpace(string(output))
+	activeSession := strings.TrimSpace(out)
 	if activeSession == "" {
 		return "", nil // No active session
 	}
@@ -121,15 +276,36 @@ func GetActiveSession() (string, error) {
 // windowName: the name for the new window.
 // directory: the working directory for the new window.
 func NewWindow(sessionName, windowName, directory string) error {
-	cmd := exec.Command("tmux", "new-window", "-t", sessionName, "-n", windowName, "-c", directory)
-	return cmd.Run()
+	_, err := newWindow(sessionName, windowName, directory)
+	return err
+}
+
+// newWindow creates a new window like NewWindow.
+// Returns the id of the pane of the window, e.g. "%3", which also
+// identifies the window even if another window has the same name.
+func newWindow(sessionName, windowName, directory string) (string, error) {
+	out, err := output("new-window", "-P", "-F", "#{pane_id}", "-t", sessionName, "-n", windowName, "-c", directory)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
 }
 
 // SplitWindow splits the current tmux window in the specified session.
 // sessionName: the name of the tmux session.
-// windowName: the name of the window to split.
+// windowName: the name of the window to split, or its index or id.
 // layout: the layout type ("horizontal" or "vertical").
-func SplitWindow(sessionName, windowName, layout string) error {
+// size: the size of the new pane, in lines or columns, or a percentage such
+// as "30%"; empty for half of the pane being split.
+func SplitWindow(sessionName, windowName, layout, size string) error {
+	_, err := splitPane(fmt.Sprintf("%s:%s", sessionName, windowName), layout, size)
+	return err
+}
+
+// splitPane splits the pane identified by target like SplitWindow. The new
+// pane comes right after the one split.
+// Returns the id of the new pane, e.g. "%3".
+func splitPane(target, layout, size string) (string, error) {
 	var layoutFlag string
 	switch layout {
 	case "horizontal":
@@ -139,19 +315,28 @@ func SplitWindow(sessionName, windowName, layout string) error {
 	default:
 		layoutFlag = "-h" // Default to horizontal split
 	}
-	cmd := exec.Command("tmux", "split-window", "-t", fmt.Sprintf("%s:%s", sessionName, windowName), fmt.Sprintf("%s", layoutFlag))
-	return cmd.Run()
+	args := []string{"split-window", "-P", "-F", "#{pane_id}", "-t", target, layoutFlag}
+	if percent, ok := strings.CutSuffix(size, "%"); ok && !Supports(SplitPercent) {
+		// Before -l accepted percentages, -p set them.
+		args = append(args, "-p", percent)
+	} else if size != "" {
+		args = append(args, "-l", size)
+	}
+	out, err := output(args...)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
 }
 
 // GetPaneBaseIndex returns the value of the global tmux variable 'pane-base-index'
 func GetPaneBaseIndex() (int, error) {
-	cmd := exec.Command("tmux", "show", "-g", "pane-base-index")
-	result, err := cmd.Output()
+	result, err := output("show", "-g", "pane-base-index")
 	if err != nil {
-		return -1, fmt.Errorf("Failed to run 'tmux show -g pane-base-index': %s", err)
+		return -1, fmt.Errorf("Failed to run 'tmux show -g pane-base-index': %w", err)
 	}
 
-	result_string := strings.TrimSpace(string(result))
+	result_string := strings.TrimSpace(result)
 	result_trimmed := strings.TrimPrefix(result_string, "pane-base-index ")
 
 	i, err := strconv.Atoi(result_trimmed)
@@ -162,35 +347,121 @@ func GetPaneBaseIndex() (int, error) {
 	return i, nil
 }
 
+// CaptureHistory returns the whole history of the pane identified by
+// target, scrollback included, with wrapped lines joined. The text is plain
+// unless escapes is true, in which case colors and attributes are kept as
+// ANSI escape sequences.
+// target: a pane target, e.g. "work:1.0" or "%3".
+func CaptureHistory(target string, escapes bool) (string, error) {
+	args := []string{"capture-pane", "-p", "-J", "-S", "-", "-t", target}
+	if escapes {
+		args = append(args, "-e")
+	}
+	return output(args...)
+}
+
+// NewSessionWindow creates a new detached tmux session like NewSession, with
+// its first window named windowName.
+// Returns the id of the pane of the window, e.g. "%3", which also
+// identifies the window in commands targeting windows.
+func NewSessionWindow(name, dirname, windowName string) (string, error) {
+	out, err := output("new-session", "-d", "-P", "-F", "#{pane_id}", "-s", name, "-c", config.ExpandHomeDir(dirname), "-n", windowName)
+	return strings.TrimSpace(out), err
+}
+
+// AddWindow creates a window named windowName after the last window of the
+// session, without selecting it.
+// Returns the id of the pane of the window, e.g. "%3".
+func AddWindow(sessionName, windowName, directory string) (string, error) {
+	out, err := output("new-window", "-d", "-P", "-F", "#{pane_id}", "-t", sessionName+":", "-n", windowName, "-c", config.ExpandHomeDir(directory))
+	return strings.TrimSpace(out), err
+}
+
+// AddPane splits the pane identified by target, starting the new pane in
+// directory, without selecting it.
+// Returns the id of the new pane, e.g. "%3".
+func AddPane(target, directory string) (string, error) {
+	out, err := output("split-window", "-d", "-P", "-F", "#{pane_id}", "-t", target, "-c", config.ExpandHomeDir(directory))
+	return strings.TrimSpace(out), err
+}
+
+// SelectLayout arranges the panes of the window identified by target with
+// layout, a layout name such as "tiled" or a layout saved from window_layout.
+func SelectLayout(target, layout string) error {
+	return run("select-layout", "-t", target, layout)
+}
+
+// SendCommand types command in the pane identified by target and runs it.
+func SendCommand(target, command string) error {
+	return run("send-keys", "-t", target, command, "C-m")
+}
+
+// ShowOption returns the value of the global tmux option with the given name,
+// e.g. "base-index".
+func ShowOption(name string) (string, error) {
+	out, err := output("show-options", "-gv", name)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
 // SendKeys sends a command to a specific tmux pane.
 // sessionName: the name of the tmux session.
-// windowName: the name of the window.
+// windowName: the name of the window, or its index or id.
 // paneIndex: the index of the pane (0-based).
 // keys: the command or keys to send.
 func SendKeys(sessionName, windowName string, paneIndex int, keys string) error {
-	target := fmt.Sprintf("%s:%s.%d", sessionName, windowName, paneIndex)
-	cmd := exec.Command("tmux", "send-keys", "-t", target)
+	return sendKeys(fmt.Sprintf("%s:%s.%d", sessionName, windowName, paneIndex), keys)
+}
+
+// sendKeys sends keys to the pane identified by target like SendKeys.
+func sendKeys(target, keys string) error {
+	args := []string{"send-keys", "-t", target}
 	if keys != "" {
-		cmd.Args = append(cmd.Args, keys, "C-m")
+		args = append(args, keys, "C-m")
 	}
-	return cmd.Run()
+	return run(args...)
 }
 
 // SwitchSession switches the tmux client to the specified session.
 // sessionName: the name of the tmux session to switch to.
 func SwitchSession(sessionName string) error {
-	cmd := exec.Command("tmux", "switch-client", "-t", sessionName)
-	return cmd.Run()
+	return run("switch-client", "-t", sessionName)
 }
 
-// expandHomeDir expands a leading ~ in a directory path to the user's home directory.
-// dirname: the directory path to expand.
-func expandHomeDir(dirname string) string {
-	if dirname != "" && dirname[:1] == "~" {
-		home, err := os.UserHomeDir()
-		if err == nil {
-			return filepath.Join(home, dirname[1:])
-		}
+// SelectWindow makes the window with the given index the active window of its session.
+// sessionName: the name of the tmux session.
+// index: the index of the window to select.
+func SelectWindow(sessionName string, index int) error {
+	return run("select-window", "-t", fmt.Sprintf("%s:%d", sessionName, index))
+}
+
+// SelectPane makes the pane with the given id the active pane of its window.
+// paneID: the unique id of the pane, e.g. "%3".
+func SelectPane(paneID string) error {
+	return run("select-pane", "-t", paneID)
+}
+
+// FocusPane makes the pane with the given id the active pane of its window,
+// and its window the active window of its session.
+// paneID: the unique id of the pane, e.g. "%3".
+func FocusPane(paneID string) error {
+	if err := run("select-window", "-t", paneID); err != nil {
+		return err
 	}
-	return dirname
+	return SelectPane(paneID)
+}
+
+// CapturePane returns the visible content of the pane identified by target,
+// including ANSI escape sequences for colors and attributes.
+// target: a session, window or pane target, e.g. "work", "work:1" or "%3".
+func CapturePane(target string) (string, error) {
+	return output("capture-pane", "-p", "-e", "-t", target)
+}
+
+// SanitizeName turns name into a valid tmux session name. Dots and colons
+// are replaced, as tmux uses them to separate sessions, windows and panes.
+func SanitizeName(name string) string {
+	return strings.NewReplacer(".", "_", ":", "_").Replace(name)
 }
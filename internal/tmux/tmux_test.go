@@ -0,0 +1,54 @@
+package tmux
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSessionLine(t *testing.T) {
+	// Output of list-sessions -F sessionFormat from tmux 3.3a.
+	tests := []struct {
+		line string
+		want SessionData
+	}{
+		{
+			line: "my work|:|1|:|0|:|1792394563|:|1792394563|:||:|/tmp/h",
+			want: SessionData{
+				Name:          "my work",
+				NumberWindows: 1,
+				Created:       time.Unix(1792394563, 0),
+				Activity:      time.Unix(1792394563, 0),
+				Path:          "/tmp/h",
+			},
+		},
+		{
+			line: "api|:|3|:|1|:|1792394555|:|1792394600|:|1792394590|:|/srv/a|:|b",
+			want: SessionData{
+				Name:          "api",
+				NumberWindows: 3,
+				Attached:      1,
+				Created:       time.Unix(1792394555, 0),
+				Activity:      time.Unix(1792394600, 0),
+				LastAttached:  time.Unix(1792394590, 0),
+				Path:          "/srv/a|:|b",
+			},
+		},
+	}
+	for _, tt := range tests {
+		got, err := parseSessionLine(tt.line)
+		if err != nil {
+			t.Errorf("parseSessionLine(%q): %v", tt.line, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseSessionLine(%q) = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseSessionLineTabs(t *testing.T) {
+	// tmux 3.3a prints tabs in formats as "_", which must not parse.
+	if _, err := parseSessionLine("demo_1_0_1792394555_1792394555__/tmp"); err == nil {
+		t.Error("expected an error for a line without separators")
+	}
+}
@@ -0,0 +1,107 @@
+package tmux
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Version is the version of the tmux binary.
+type Version struct {
+	Major int    // Major version, e.g. 3 for "tmux 3.3a"
+	Minor int    // Minor version, e.g. 3 for "tmux 3.3a"
+	Raw   string // Version as printed by tmux -V, without the "tmux " prefix
+}
+
+// MinVersion is the oldest tmux version muxie supports.
+var MinVersion = Version{Major: 3, Minor: 0, Raw: "3.0"}
+
+// Feature is a tmux feature muxie relies on that older versions lack.
+type Feature struct {
+	Name  string // What the feature is, e.g. "display-popup"
+	Major int    // Major version the feature appeared in
+	Minor int    // Minor version the feature appeared in
+}
+
+// Features of tmux muxie uses when available, degrading gracefully otherwise.
+var (
+	// Popup is the display-popup command, used to run muxie in a popup.
+	Popup = Feature{Name: "display-popup", Major: 3, Minor: 2}
+	// SessionEnv is new-session -e, setting environment variables of the
+	// first window. Otherwise they are set with set-environment once the
+	// session is created.
+	SessionEnv = Feature{Name: "new-session -e", Major: 3, Minor: 2}
+	// SplitPercent is split-window -l with a percentage. Otherwise
+	// percentages are passed to -p.
+	SplitPercent = Feature{Name: "split-window -l with a percentage", Major: 3, Minor: 1}
+)
+
+// Features lists every Feature, for diagnostics.
+var Features = []Feature{Popup, SessionEnv, SplitPercent}
+
+var (
+	versionOnce sync.Once
+	version     Version
+	versionErr  error
+)
+
+// CurrentVersion returns the version of the tmux binary. tmux -V is only
+// run the first time; later calls return the same result.
+func CurrentVersion() (Version, error) {
+	versionOnce.Do(func() {
+		version, versionErr = GetVersion()
+		if versionErr != nil {
+			logger.Warn("could not get tmux version", "err", versionErr)
+		}
+	})
+	return version, versionErr
+}
+
+// Supports reports whether the tmux binary has the feature f. Versions
+// that cannot be parsed, such as "master" for builds from source, are
+// assumed to have every feature.
+func Supports(f Feature) bool {
+	v, err := CurrentVersion()
+	return err != nil || v.AtLeast(f.Major, f.Minor)
+}
+
+// CheckVersion returns an error if the tmux binary is older than MinVersion.
+func CheckVersion() error {
+	v, err := CurrentVersion()
+	if err != nil || v.AtLeast(MinVersion.Major, MinVersion.Minor) {
+		return nil
+	}
+	return fmt.Errorf("tmux %s is older than %s, the oldest version muxie supports", v.Raw, MinVersion.Raw)
+}
+
+// versionPattern matches the numeric part of tmux versions such as "3.3a"
+// or "next-3.4".
+var versionPattern = regexp.MustCompile(`(\d+)\.(\d+)`)
+
+// GetVersion returns the version of the tmux binary, from tmux -V.
+func GetVersion() (Version, error) {
+	out, err := output("-V")
+	if err != nil {
+		return Version{}, err
+	}
+	return parseVersion(out)
+}
+
+// parseVersion parses the output of tmux -V, e.g. "tmux 3.3a".
+func parseVersion(out string) (Version, error) {
+	raw := strings.TrimPrefix(strings.TrimSpace(out), "tmux ")
+	m := versionPattern.FindStringSubmatch(raw)
+	if m == nil {
+		return Version{Raw: raw}, fmt.Errorf("unexpected tmux version %q", raw)
+	}
+	major, _ := strconv.Atoi(m[1])
+	minor, _ := strconv.Atoi(m[2])
+	return Version{Major: major, Minor: minor, Raw: raw}, nil
+}
+
+// AtLeast reports whether the version is major.minor or later.
+func (v Version) AtLeast(major, minor int) bool {
+	return v.Major > major || v.Major == major && v.Minor >= minor
+}
@@ -0,0 +1,58 @@
+package tmux
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		out  string
+		want Version
+		err  bool
+	}{
+		{out: "tmux 3.3a\n", want: Version{Major: 3, Minor: 3, Raw: "3.3a"}},
+		{out: "tmux 3.0", want: Version{Major: 3, Minor: 0, Raw: "3.0"}},
+		{out: "tmux 2.9a", want: Version{Major: 2, Minor: 9, Raw: "2.9a"}},
+		{out: "tmux next-3.4", want: Version{Major: 3, Minor: 4, Raw: "next-3.4"}},
+		{out: "tmux 3.10", want: Version{Major: 3, Minor: 10, Raw: "3.10"}},
+		{out: "tmux master", want: Version{Raw: "master"}, err: true},
+	}
+	for _, tt := range tests {
+		got, err := parseVersion(tt.out)
+		if (err != nil) != tt.err {
+			t.Errorf("parseVersion(%q) error = %v, want error %v", tt.out, err, tt.err)
+		}
+		if got != tt.want {
+			t.Errorf("parseVersion(%q) = %+v, want %+v", tt.out, got, tt.want)
+		}
+	}
+}
+
+func TestSupports(t *testing.T) {
+	// Mark the version as known so tmux -V is never run.
+	versionOnce.Do(func() {})
+	t.Cleanup(func() { version, versionErr = Version{}, nil })
+
+	tests := []struct {
+		version Version
+		err     error
+		feature Feature
+		want    bool
+	}{
+		{version: Version{Major: 3, Minor: 3}, feature: Popup, want: true},
+		{version: Version{Major: 3, Minor: 2}, feature: Popup, want: true},
+		{version: Version{Major: 3, Minor: 1}, feature: Popup, want: false},
+		{version: Version{Major: 3, Minor: 1}, feature: SplitPercent, want: true},
+		{version: Version{Major: 3, Minor: 0}, feature: SplitPercent, want: false},
+		{version: Version{Major: 4, Minor: 0}, feature: SessionEnv, want: true},
+		{version: Version{Major: 2, Minor: 9}, feature: SessionEnv, want: false},
+		{version: Version{Raw: "master"}, err: errors.New("unexpected tmux version"), feature: Popup, want: true},
+	}
+	for _, tt := range tests {
+		version, versionErr = tt.version, tt.err
+		if got := Supports(tt.feature); got != tt.want {
+			t.Errorf("tmux %+v: Supports(%s) = %v, want %v", tt.version, tt.feature.Name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Package tmux provides utilities for interacting with and managing tmux sessions.
+package tmux
+
+import (
+	"fmt"
+
+	"github.com/phanorcoll/muxie/internal/config"
+)
+
+// NewWindowFromTemplate creates a window in the running session with the
+// given name from the window template w, with its panes and their commands,
+// and makes it the active window of the session. The window is named after
+// the template, with a number appended if the session already has a window
+// with that name, and is empty if the template has no name. If creating a
+// pane fails, the window is killed.
+// directory: the working directory of the panes without one in the template.
+// Returns the name of the window.
+func NewWindowFromTemplate(sessionName, directory string, w config.Window) (string, error) {
+	directory = config.ExpandHomeDir(directory)
+	if w.Name == "" {
+		return "", run("new-window", "-t", sessionName, "-c", directory)
+	}
+	name, err := uniqueWindowName(sessionName, w.Name)
+	if err != nil {
+		return "", err
+	}
+	w.Name = name
+	pane, err := newWindow(sessionName, w.Name, directory)
+	if err != nil {
+		return "", err
+	}
+	if step, err := buildPanes(pane, directory, w, 0); err != nil {
+		err = fmt.Errorf("%s: %w", step, err)
+		if kerr := run("kill-window", "-t", pane); kerr != nil {
+			err = fmt.Errorf("%w (rollback: %v)", err, kerr)
+		}
+		return "", err
+	}
+	return w.Name, nil
+}
+
+// uniqueWindowName returns name, or name followed by the first number from 2
+// that makes it unique among the windows of the given session, so windows
+// added from the same template can be told apart.
+func uniqueWindowName(sessionName, name string) (string, error) {
+	windows, err := GetWindowsList()
+	if err != nil {
+		return "", err
+	}
+	taken := make(map[string]bool)
+	for _, w := range windows[sessionName] {
+		taken[w.Name] = true
+	}
+	unique := name
+	for i := 2; taken[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d", name, i)
+	}
+	return unique, nil
+}
+
+// RenameWindow renames the window with the given index of a session.
+func RenameWindow(sessionName string, index int, name string) error {
+	return run("rename-window", "-t", fmt.Sprintf("%s:%d", sessionName, index), name)
+}
+
+// KillPane kills the pane with the given id, e.g. "%3". Killing the last
+// pane of a window kills the window.
+func KillPane(paneID string) error {
+	if err := run("kill-pane", "-t", paneID); err != nil {
+		logger.Error("could not kill pane", "pane", paneID, "err", err)
+		return err
+	}
+	return nil
+}
+
+// MoveWindow moves the window with the given index of a session to the
+// session named dstSession, at its first free index, without changing the
+// active window of either session.
+func MoveWindow(sessionName string, index int, dstSession string) error {
+	return run("move-window", "-d", "-s", fmt.Sprintf("%s:%d", sessionName, index), "-t", dstSession+":")
+}
+
+// SwapWindows swaps the window with the given index of a session with the
+// window other of the same session, given by index or name, without
+// changing its active window.
+func SwapWindows(sessionName string, index int, other string) error {
+	return run("swap-window", "-d", "-s", fmt.Sprintf("%s:%d", sessionName, index), "-t", sessionName+":"+other)
+}
@@ -0,0 +1,83 @@
+// Package tui contains terminal user interface commands and related functionality.
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// actionMsg reports the outcome of an action run by actionCmd.
+// verb: what the action does, e.g. "kill".
+// failed: the targets the action failed on.
+// status: the summary shown once an action on several targets is done.
+// err: the errors of the failed targets.
+// retry: the command running the action again on the failed targets, nil if
+// retrying is pointless.
+// quit: whether to quit once the action succeeds.
+// worktrees: whether the action may have changed the worktrees, for the
+// config sessions to be expanded again.
+type actionMsg struct {
+	verb      string
+	failed    []string
+	status    string
+	err       error
+	retry     tea.Cmd
+	quit      bool
+	worktrees bool
+}
+
+// actionCmd returns a command running op on every target, e.g. the names of
+// the sessions to kill, and reporting the outcome in an actionMsg. past is
+// the past tense of verb, used in the summary of actions on several targets.
+// If quit is true, the TUI quits once op succeeded on every target.
+func actionCmd(verb, past string, targets []string, quit bool, op func(target string) error) tea.Cmd {
+	return func() tea.Msg {
+		var failed []string
+		var errs []error
+		for _, target := range targets {
+			if err := op(target); err != nil {
+				failed = append(failed, target)
+				errs = append(errs, err)
+			}
+		}
+		msg := actionMsg{verb: verb, failed: failed, quit: quit}
+		if len(targets) > 1 {
+			msg.status = fmt.Sprintf("%s %d of %d sessions", past, len(targets)-len(failed), len(targets))
+		}
+		if len(failed) > 0 {
+			msg.err = errors.Join(errs...)
+			msg.retry = actionCmd(verb, past, failed, quit, op)
+		}
+		return msg
+	}
+}
+
+// worktreeCmd returns cmd, a command returned by actionCmd, reporting that
+// the action may have changed the worktrees, and so does its retry.
+func worktreeCmd(cmd tea.Cmd) tea.Cmd {
+	return func() tea.Msg {
+		msg := cmd().(actionMsg)
+		msg.worktrees = true
+		if msg.retry != nil {
+			msg.retry = worktreeCmd(msg.retry)
+		}
+		return msg
+	}
+}
+
+// renderFailure renders the panel showing the error of a failed action in
+// a box of the given width. Retrying is only offered if the action has a retry.
+func renderFailure(f actionMsg, width int) string {
+	title := errorStyle(fmt.Sprintf("%s could not %s %s", icons.Error, f.verb, strings.Join(f.failed, ", ")))
+	detail := inputHelpStyle.Width(max(1, width)).Render(f.err.Error())
+	help := "esc - dismiss"
+	if f.retry != nil {
+		help = "enter - retry " + icons.Bullet + " " + help
+	}
+	help = inputHelpStyle.Render(help)
+	return lipgloss.JoinVertical(lipgloss.Left, title, "", detail, "", help)
+}
@@ -0,0 +1,85 @@
+// Package tui contains terminal user interface commands and related functionality.
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/phanorcoll/muxie/internal/capture"
+	"github.com/phanorcoll/muxie/internal/config"
+)
+
+// captureTarget is what the capture dialog applies to.
+// label: how the target is named in messages, e.g. "work:1".
+// dir: the directory the capture is written to if none is typed.
+type captureTarget struct {
+	session string
+	filter  capture.Filter
+	label   string
+	dir     string
+}
+
+// defaultCaptureDir returns the directory a capture of the named session
+// is written to by default, named after the session and the current time.
+func defaultCaptureDir(session string) string {
+	// Worktree sessions have slashes in their names.
+	name := strings.ReplaceAll(session, "/", "_")
+	return filepath.Join("~", "muxie-captures", name+"-"+time.Now().Format("20060102-150405"))
+}
+
+// openCapture opens the dialog asking for the directory to write the
+// history of the selected session, window or pane to.
+// Returns a status message instead if the selection cannot be captured.
+func (m *Model) openCapture() tea.Cmd {
+	var t captureTarget
+	switch i := m.sessionList.SelectedItem().(type) {
+	case session:
+		if !i.isRunning {
+			return m.sessionList.NewStatusMessage(errorMessage("not running"))
+		}
+		t = captureTarget{session: i.sessionName, label: i.sessionName}
+	case windowItem:
+		index := fmt.Sprint(i.window.Index)
+		t = captureTarget{session: i.sessionName, filter: capture.Filter{Window: index}, label: i.sessionName + ":" + index}
+	case paneItem:
+		index := fmt.Sprint(i.windowIndex)
+		t = captureTarget{session: i.sessionName, filter: capture.Filter{Window: index, Pane: i.pane.ID},
+			label: fmt.Sprintf("%s:%s.%d", i.sessionName, index, i.pane.Index)}
+	default:
+		return m.sessionList.NewStatusMessage(errorMessage("select a session"))
+	}
+	t.dir = defaultCaptureDir(t.session)
+	m.pendingCapture = t
+	m.showInput = true
+	m.sessionInput.Placeholder = "directory"
+	m.sessionInput.CharLimit = 0
+	m.sessionInput.Focus()
+	m.statusData.action = "c"
+	m.statusData.icon = icons.Add
+	m.statusData.color = addColor
+	m.statusData.actionTitle = fmt.Sprintf("Capture %s to", t.label)
+	m.statusData.detail = "empty for " + t.dir
+	return nil
+}
+
+// captureCmd returns a command writing the capture of t to dir, reporting
+// where it was written once done.
+func captureCmd(t captureTarget, dir string) tea.Cmd {
+	cmd := actionCmd("capture", "captured", []string{t.label}, false, func(string) error {
+		_, err := capture.Capture(t.session, t.filter, config.ExpandHomeDir(dir))
+		return err
+	})
+	return func() tea.Msg {
+		msg := cmd().(actionMsg)
+		if msg.err != nil {
+			// Retrying reports where the capture was written too.
+			msg.retry = captureCmd(t, dir)
+		} else {
+			msg.status = "captured to " + dir
+		}
+		return msg
+	}
+}
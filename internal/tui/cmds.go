@@ -2,7 +2,8 @@
 package tui
 
 import (
-	"log"
+	"log/slog"
+	"time"
 
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
@@ -18,77 +19,129 @@ type sessionsResponseMsg struct {
 	Err           error
 }
 
+// worktreesMsg carries the config sessions with worktree sessions expanded,
+// as returned by worktreeSessions.
+type worktreesMsg struct {
+	sessions []config.Session
+	parents  map[string]string
+	err      error
+}
+
+// expandWorktreesCmd returns a command expanding the sessions of cfg that
+// have worktrees enabled into one session per worktree. As it runs git, it
+// is only run when the TUI starts and after actions that may change the
+// worktrees, rather than on every refresh.
+func expandWorktreesCmd(cfg *config.Config) tea.Cmd {
+	return func() tea.Msg {
+		sessions, parents, err := worktreeSessions(cfg.Sessions)
+		return worktreesMsg{sessions: sessions, parents: parents, err: err}
+	}
+}
+
 // activeResponseMsg represents a message containing the name of the currently active session.
 type activeResponseMsg struct {
 	ActiveSession string
 }
 
+// refreshTickMsg is sent periodically to refresh the list of sessions.
+type refreshTickMsg struct{}
+
 // getSessionsCmd retrieves the list of tmux sessions and the currently active session.
 // It returns a sessionsResponseMsg containing the sessions list, the active session,
-// and any error encountered during retrieval.
-func getSessionsCmd(config *config.Config) tea.Cmd {
-	sl, err := tmux.GetSessionsList()
-	if err != nil {
-		return func() tea.Msg {
+// and any error encountered during retrieval. Config sessions come first, in the
+// order of the config file, followed by the other running sessions; the list
+// is sorted for display by arrangeSessions. configSessions are the config
+// sessions with worktree sessions expanded.
+func getSessionsCmd(configSessions []config.Session, logger *slog.Logger) tea.Cmd {
+	return func() tea.Msg {
+		sl, err := tmux.GetSessionsList()
+		if err != nil {
 			return sessionsResponseMsg{
 				Err: err,
 			}
 		}
-	}
-	var sessions []list.Item
-	activeSession, err := tmux.GetActiveSession()
-
-	for _, sessionInfo := range config.Sessions {
-		sessions = append(sessions, session{
-			sessionName:   sessionInfo.Name,
-			numWindows:    len(sessionInfo.Windows),
-			activeSession: activeSession,
-			isFromConfig:  true,
-			isRunning:     false,
-		})
-	}
-
-	existingSessions := make(map[string]bool)
-	for i, s := range sessions {
-		for _, runningSession := range sl {
-			if s.(session).sessionName == runningSession.Name {
-				sessionItem := s.(session)
-				sessionItem.isRunning = true
-				sessionItem.numWindows = runningSession.NumberWindows
-				sessions[i] = sessionItem
-				existingSessions[sessionItem.sessionName] = true
+		var sessions []list.Item
+		activeSession, err := tmux.GetActiveSession()
+		if err != nil {
+			logger.Error("could not get active session", "err", err)
+			return sessionsResponseMsg{
+				Err: err,
 			}
 		}
-	}
+		// Windows are only used to expand sessions into a tree, so the
+		// list is still useful if they cannot be retrieved.
+		windows, err := tmux.GetWindowsList()
+		if err != nil {
+			logger.Warn("could not list windows", "err", err)
+		}
 
-	for _, sessionInfo := range sl {
-		if !existingSessions[sessionInfo.Name] {
+		for _, sessionInfo := range configSessions {
 			sessions = append(sessions, session{
 				sessionName:   sessionInfo.Name,
-				numWindows:    sessionInfo.NumberWindows,
+				numWindows:    len(sessionInfo.Windows),
+				tags:          sessionInfo.Tags,
+				source:        sessionInfo.Source,
 				activeSession: activeSession,
-				isFromConfig:  false,
-				isRunning:     true,
+				isFromConfig:  true,
+				isRunning:     false,
 			})
 		}
-	}
 
-	if err != nil {
-		log.Println("Error getting active session:", err)
-		return func() tea.Msg {
-			return sessionsResponseMsg{
-				Err: err,
+		existingSessions := make(map[string]bool)
+		for i, s := range sessions {
+			for _, runningSession := range sl {
+				if s.(session).sessionName == runningSession.Name {
+					sessionItem := s.(session)
+					sessionItem.isRunning = true
+					sessionItem.numWindows = runningSession.NumberWindows
+					sessionItem.attached = runningSession.Attached
+					sessionItem.path = runningSession.Path
+					sessionItem.created = runningSession.Created
+					sessionItem.activity = runningSession.Activity
+					sessionItem.lastAttached = runningSession.LastAttached
+					sessionItem.windows = windows[runningSession.Name]
+					sessions[i] = sessionItem
+					existingSessions[sessionItem.sessionName] = true
+				}
 			}
 		}
-	}
-	return func() tea.Msg {
+
+		for _, sessionInfo := range sl {
+			if !existingSessions[sessionInfo.Name] {
+				sessions = append(sessions, session{
+					sessionName:   sessionInfo.Name,
+					numWindows:    sessionInfo.NumberWindows,
+					attached:      sessionInfo.Attached,
+					path:          sessionInfo.Path,
+					created:       sessionInfo.Created,
+					activity:      sessionInfo.Activity,
+					lastAttached:  sessionInfo.LastAttached,
+					windows:       windows[sessionInfo.Name],
+					activeSession: activeSession,
+					isFromConfig:  false,
+					isRunning:     true,
+				})
+			}
+		}
+
 		return sessionsResponseMsg{
-			SessionsList:  moveActiveSessionToTop(sessions, activeSession),
+			SessionsList:  sessions,
 			ActiveSession: activeSession,
 		}
 	}
 }
 
+// refreshCmd schedules the next refreshTickMsg after the given interval.
+// Returns nil if interval is 0, which disables live updates.
+func refreshCmd(interval time.Duration) tea.Cmd {
+	if interval <= 0 {
+		return nil
+	}
+	return tea.Tick(interval, func(time.Time) tea.Msg {
+		return refreshTickMsg{}
+	})
+}
+
 // moveActiveSessionToTop reorders the sessions so that the active session is first,
 // followed by other running sessions, then the rest.
 func moveActiveSessionToTop(sessions []list.Item, activeSession string) []list.Item {
@@ -111,7 +164,6 @@ func moveActiveSessionToTop(sessions []list.Item, activeSession string) []list.I
 		}
 	}
 
-
 	combined := append(append(active, running...), others...)
 
 	// Find the session that shows up right before the "others" and
@@ -120,10 +172,23 @@ func moveActiveSessionToTop(sessions []list.Item, activeSession string) []list.I
 	// line of white space underneath. This helps separate the active/running
 	// sessions from the unstarted config sessions.
 	if 0 < len(others) && len(others) < len(combined) {
-		sess := combined[len(combined) - len(others) - 1].(session)
+		sess := combined[len(combined)-len(others)-1].(session)
 		sess.addSpacingUnder = true
-		combined[len(combined) - len(others) - 1] = sess
+		combined[len(combined)-len(others)-1] = sess
 	}
 
 	return combined
 }
+
+// checkVersionCmd returns a command checking that tmux is not older than
+// the oldest version muxie supports. A failure is shown in the error panel,
+// without a retry as tmux is not upgraded while muxie runs; once dismissed,
+// muxie keeps running with what tmux supports.
+func checkVersionCmd() tea.Cmd {
+	return func() tea.Msg {
+		if err := tmux.CheckVersion(); err != nil {
+			return actionMsg{verb: "use", failed: []string{"tmux"}, err: err}
+		}
+		return actionMsg{verb: "use"}
+	}
+}
@@ -0,0 +1,88 @@
+// Package tui contains terminal user interface commands and related functionality.
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/phanorcoll/muxie/internal/config"
+)
+
+// itemFormatter formats the name of a session as displayed in the list.
+type itemFormatter interface {
+	Format(s session) string
+}
+
+// formatter is the item formatter in use, set by NewModel.
+var formatter itemFormatter = asIsFormatter{}
+
+// newItemFormatter returns the item formatter described by the display
+// section of the config file. A template takes precedence over the name style.
+// Returns an error if the name style is unknown or the template does not parse.
+func newItemFormatter(cfg config.Display) (itemFormatter, error) {
+	if cfg.Template != "" {
+		tmpl, err := template.New("item").Parse(cfg.Template)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse display template: %w", err)
+		}
+		return templateFormatter{tmpl: tmpl}, nil
+	}
+	switch cfg.Name {
+	case "", "as-is":
+		return asIsFormatter{}, nil
+	case "title":
+		return titleFormatter{}, nil
+	}
+	return nil, fmt.Errorf("unknown display name style %q, expected as-is or title", cfg.Name)
+}
+
+// asIsFormatter displays session names exactly as tmux reports them.
+type asIsFormatter struct{}
+
+func (asIsFormatter) Format(s session) string { return s.sessionName }
+
+// titleFormatter lowercases session names and capitalizes their first letter.
+type titleFormatter struct{}
+
+func (titleFormatter) Format(s session) string {
+	if len(s.sessionName) == 0 {
+		return ""
+	}
+	r := []rune(strings.ToLower(s.sessionName))
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// templateItem is the data available to display templates.
+type templateItem struct {
+	Name       string // Name of the session
+	Windows    int    // Number of windows
+	Attached   int    // Number of attached clients
+	Path       string // Working directory, with the home directory shortened to ~
+	Running    bool   // Whether the session is running
+	FromConfig bool   // Whether the session is defined in the config file
+}
+
+// templateFormatter displays sessions using a user-defined text/template,
+// e.g. "{{.Name}} ({{.Attached}})".
+type templateFormatter struct {
+	tmpl *template.Template
+}
+
+func (f templateFormatter) Format(s session) string {
+	var b strings.Builder
+	err := f.tmpl.Execute(&b, templateItem{
+		Name:       s.sessionName,
+		Windows:    s.numWindows,
+		Attached:   s.attached,
+		Path:       shortenPath(s.path),
+		Running:    s.isRunning,
+		FromConfig: s.isFromConfig,
+	})
+	if err != nil {
+		return s.sessionName
+	}
+	return b.String()
+}
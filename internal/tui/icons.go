@@ -0,0 +1,171 @@
+// Package tui contains terminal user interface commands and related functionality.
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// defaultIcons is the icon set used when none is set in the config file.
+// It picks an icon set based on what the terminal is likely to support.
+const defaultIcons = "auto"
+
+// iconSet holds the glyphs used to render the TUI.
+type iconSet struct {
+	Logo       string // Before the name in the header
+	Cursor     string // In front of the selected item
+	Config     string // Session defined in the config file
+	Running    string // Running session not defined in the config file
+	Stopped    string // Config session that has not been started
+	Project    string // Discovered git repository that has not been started
+	Windows    string // After the number of windows of a session
+	ActiveMark string // After the name of the active session
+	Active     string // Before the "active" label
+	Attached   string // Before the number of attached clients
+	Error      string // Before error messages
+	Idle       string // Status icon when no action is in progress
+	Add        string // Status icon of the add session dialog
+	Rename     string // Status icon of the rename session dialog
+	Kill       string // Status icon of the kill session dialog
+	Marked     string // In front of sessions selected for bulk actions
+	Bullet     string // Separator in the header and previews
+	TreeBranch string // Tree item followed by siblings
+	TreeLast   string // Last tree item
+	TreePipe   string // Continuation of a tree branch
+}
+
+var (
+	// nerdFontIcons requires a Nerd Font, see https://www.nerdfonts.com.
+	nerdFontIcons = iconSet{
+		Logo:       "\U000f1316",
+		Cursor:     "",
+		Config:     "\U000f05fc",
+		Running:    "\U000f018d",
+		Stopped:    "\U000f011c",
+		Project:    "\U000f02a2",
+		Windows:    "\U000f10ac",
+		ActiveMark: "",
+		Active:     "\U000f0793",
+		Attached:   "\U000f0379",
+		Error:      "\U000f05fc",
+		Idle:       "",
+		Add:        "",
+		Rename:     "\U000f0455",
+		Kill:       "\U000f05e8",
+		Marked:     "󰄲",
+		Bullet:     "•",
+		TreeBranch: "├─",
+		TreeLast:   "└─",
+		TreePipe:   "│ ",
+	}
+	// unicodeIcons only uses glyphs found in most monospace fonts.
+	unicodeIcons = iconSet{
+		Logo:       "▦",
+		Cursor:     "▸",
+		Config:     "◆",
+		Running:    "●",
+		Stopped:    "○",
+		Project:    "◇",
+		Windows:    "▢",
+		ActiveMark: "",
+		Active:     "★",
+		Attached:   "◉",
+		Error:      "✗",
+		Idle:       "≡",
+		Add:        "+",
+		Rename:     "✎",
+		Kill:       "✗",
+		Marked:     "✓",
+		Bullet:     "•",
+		TreeBranch: "├─",
+		TreeLast:   "└─",
+		TreePipe:   "│ ",
+	}
+	// asciiIcons works on any terminal.
+	asciiIcons = iconSet{
+		Logo:       "#",
+		Cursor:     ">",
+		Config:     "c",
+		Running:    "r",
+		Stopped:    "-",
+		Project:    "g",
+		Windows:    "w",
+		ActiveMark: "",
+		Active:     "*",
+		Attached:   "@",
+		Error:      "x",
+		Idle:       "=",
+		Add:        "+",
+		Rename:     "~",
+		Kill:       "x",
+		Marked:     "+",
+		Bullet:     "-",
+		TreeBranch: "|-",
+		TreeLast:   "`-",
+		TreePipe:   "| ",
+	}
+
+	iconSets = map[string]iconSet{
+		"nerdfont": nerdFontIcons,
+		"unicode":  unicodeIcons,
+		"ascii":    asciiIcons,
+	}
+)
+
+// icons is the icon set in use, set by NewModel.
+var icons = nerdFontIcons
+
+// newIconSet returns the icon set with the given name. "auto" picks
+// ascii on terminals without UTF-8 support, unicode in CI where Nerd
+// Fonts are rarely installed, and nerdfont otherwise.
+// Returns an error if the name is unknown.
+func newIconSet(name string) (iconSet, error) {
+	set, ok := iconSets[IconSet(name)]
+	if !ok {
+		return iconSet{}, fmt.Errorf("unknown icon set %q, available icon sets are auto, nerdfont, unicode and ascii", name)
+	}
+	return set, nil
+}
+
+// IconSet returns the name of the icon set used for the icons setting of
+// the config, resolving "auto" for the current environment.
+func IconSet(name string) string {
+	if name == "" {
+		name = defaultIcons
+	}
+	if name == "auto" {
+		return detectIconSet()
+	}
+	return name
+}
+
+// detectIconSet returns the name of the icon set most likely to render
+// correctly, based on the environment.
+func detectIconSet() string {
+	term := os.Getenv("TERM")
+	if term == "linux" || term == "dumb" || !utf8Locale() {
+		return "ascii"
+	}
+	if os.Getenv("CI") != "" {
+		return "unicode"
+	}
+	return "nerdfont"
+}
+
+// utf8Locale reports whether the locale from the environment uses UTF-8.
+// An unset locale is assumed to be UTF-8, as on most macOS setups.
+func utf8Locale() bool {
+	for _, env := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if v := os.Getenv(env); v != "" {
+			v = strings.ToLower(v)
+			return strings.Contains(v, "utf-8") || strings.Contains(v, "utf8")
+		}
+	}
+	return true
+}
+
+// errorMessage formats msg as an error for the list status bar.
+func errorMessage(msg string) string {
+	return errorStyle(icons.Error + " " + msg)
+}
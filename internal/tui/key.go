@@ -1,19 +1,39 @@
 // Package tui contains terminal user interface commands and related functionality.
 package tui
 
-import "github.com/charmbracelet/bubbles/key"
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+)
 
 // keyMap defines key bindings for navigating the TUI.
 type keyMap struct {
-	Start  key.Binding
-	Rename key.Binding
-	Kill   key.Binding
-	Add    key.Binding
-	Escape key.Binding
-	Enter  key.Binding
-	Help   key.Binding
-	Quit   key.Binding
-	Filter key.Binding
+	Start    key.Binding
+	Rename   key.Binding
+	Kill     key.Binding
+	Add      key.Binding
+	Escape   key.Binding
+	Enter    key.Binding
+	Help     key.Binding
+	Quit     key.Binding
+	Filter   key.Binding
+	Expand   key.Binding
+	Preview  key.Binding
+	Up       key.Binding
+	Down     key.Binding
+	Select   key.Binding
+	Sort     key.Binding
+	Group    key.Binding
+	Worktree key.Binding
+	Restore  key.Binding
+	Capture  key.Binding
+	Window   key.Binding
+	Move     key.Binding
+	Swap     key.Binding
 }
 
 // defaultKeyMap provides the default key bindings for moving up and down in the TUI.
@@ -32,7 +52,7 @@ var defaultKeyMap = keyMap{
 	),
 	Kill: key.NewBinding(
 		key.WithKeys("d"),
-		key.WithHelp("d", "kill session"),
+		key.WithHelp("d", "kill"),
 	),
 	Help: key.NewBinding(
 		key.WithKeys("?"),
@@ -54,6 +74,208 @@ var defaultKeyMap = keyMap{
 		key.WithKeys("/"),
 		key.WithHelp("/", "filter"),
 	),
+	Expand: key.NewBinding(
+		key.WithKeys("tab"),
+		key.WithHelp("tab", "expand"),
+	),
+	Preview: key.NewBinding(
+		key.WithKeys("p"),
+		key.WithHelp("p", "toggle preview"),
+	),
+	Up: key.NewBinding(
+		key.WithKeys("up", "k"),
+		key.WithHelp("↑/k", "up"),
+	),
+	Down: key.NewBinding(
+		key.WithKeys("down", "j"),
+		key.WithHelp("↓/j", "down"),
+	),
+	Select: key.NewBinding(
+		key.WithKeys(" "),
+		key.WithHelp("space", "select"),
+	),
+	Sort: key.NewBinding(
+		key.WithKeys("o"),
+		key.WithHelp("o", "sort"),
+	),
+	Group: key.NewBinding(
+		key.WithKeys("O"),
+		key.WithHelp("O", "group"),
+	),
+	Worktree: key.NewBinding(
+		key.WithKeys("w"),
+		key.WithHelp("w", "new worktree"),
+	),
+	Restore: key.NewBinding(
+		key.WithKeys("R"),
+		key.WithHelp("R", "restore saved"),
+	),
+	Capture: key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "capture"),
+	),
+	Window: key.NewBinding(
+		key.WithKeys("n"),
+		key.WithHelp("n", "new window"),
+	),
+	Move: key.NewBinding(
+		key.WithKeys("m"),
+		key.WithHelp("m", "move window"),
+	),
+	Swap: key.NewBinding(
+		key.WithKeys("x"),
+		key.WithHelp("x", "swap window"),
+	),
+}
+
+// keyModifiers are the modifiers accepted in custom key bindings, e.g. "ctrl+x".
+var keyModifiers = map[string]bool{"ctrl": true, "alt": true, "shift": true}
+
+// actions returns the bindings of the key map indexed by the action name
+// used in the keys section of the config file.
+func (k *keyMap) actions() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"start":    &k.Start,
+		"rename":   &k.Rename,
+		"kill":     &k.Kill,
+		"add":      &k.Add,
+		"escape":   &k.Escape,
+		"enter":    &k.Enter,
+		"help":     &k.Help,
+		"quit":     &k.Quit,
+		"filter":   &k.Filter,
+		"expand":   &k.Expand,
+		"preview":  &k.Preview,
+		"up":       &k.Up,
+		"down":     &k.Down,
+		"select":   &k.Select,
+		"sort":     &k.Sort,
+		"group":    &k.Group,
+		"worktree": &k.Worktree,
+		"restore":  &k.Restore,
+		"capture":  &k.Capture,
+		"window":   &k.Window,
+		"move":     &k.Move,
+		"swap":     &k.Swap,
+	}
+}
+
+// listKeys are the bindings of the session list that cannot be remapped:
+// paging and jumping to either end of the list. They use keys muxie binds
+// nothing to by default, rather than the letters of the list's own defaults,
+// so moving an action off a letter never makes it page the list.
+var listKeys = map[string]key.Binding{
+	"next page":     key.NewBinding(key.WithKeys("right", "pgdown")),
+	"previous page": key.NewBinding(key.WithKeys("left", "pgup")),
+	"first item":    key.NewBinding(key.WithKeys("home")),
+	"last item":     key.NewBinding(key.WithKeys("end")),
+}
+
+// newKeyMap returns the default key map with the bindings of the given actions
+// replaced by the configured keys. Returns an error if an action is unknown,
+// a key is malformed or a key ends up bound to more than one action, or to
+// an action and one of listKeys.
+func newKeyMap(overrides map[string][]string) (keyMap, error) {
+	k := defaultKeyMap
+	actions := k.actions()
+
+	for action, keys := range overrides {
+		b, ok := actions[action]
+		if !ok {
+			return keyMap{}, fmt.Errorf("unknown key action %q", action)
+		}
+		if len(keys) == 0 {
+			return keyMap{}, fmt.Errorf("no keys bound to action %q", action)
+		}
+		normalized := make([]string, len(keys))
+		for i, kk := range keys {
+			n, err := normalizeKey(kk)
+			if err != nil {
+				return keyMap{}, fmt.Errorf("action %q: %w", action, err)
+			}
+			normalized[i] = n
+		}
+		keys = normalized
+		*b = key.NewBinding(
+			key.WithKeys(keys...),
+			key.WithHelp(strings.Join(keys, "/"), b.Help().Desc),
+		)
+	}
+
+	// Go through the actions in a stable order so conflicts are reported
+	// the same way every time.
+	names := make([]string, 0, len(actions))
+	for name := range actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	boundTo := make(map[string]string)
+	for name, b := range listKeys {
+		for _, kk := range b.Keys() {
+			boundTo[kk] = name
+		}
+	}
+	for _, name := range names {
+		for _, kk := range actions[name].Keys() {
+			if other, ok := boundTo[kk]; ok {
+				return keyMap{}, fmt.Errorf("key %q is bound to both %q and %q", kk, other, name)
+			}
+			boundTo[kk] = name
+		}
+	}
+
+	return k, nil
+}
+
+// normalizeKey validates a key as written in the config file and returns it
+// in the form reported by Bubble Tea, e.g. "ctrl+x", "alt+d" or " " for space.
+// Single characters keep their case, so "D" is shift+d.
+func normalizeKey(k string) (string, error) {
+	k = strings.TrimSpace(k)
+	if k == "" {
+		return "", fmt.Errorf("empty key")
+	}
+	if k == "+" {
+		return k, nil
+	}
+	parts := strings.Split(k, "+")
+	for i, mod := range parts[:len(parts)-1] {
+		mod = strings.ToLower(mod)
+		if !keyModifiers[mod] {
+			return "", fmt.Errorf("unknown modifier %q in key %q", mod, k)
+		}
+		parts[i] = mod
+	}
+	last := parts[len(parts)-1]
+	switch {
+	case last == "":
+		return "", fmt.Errorf("missing key after modifier in %q", k)
+	case strings.EqualFold(last, "space"):
+		last = " "
+	case len([]rune(last)) > 1:
+		// Named keys such as "enter" or "pgup" are reported in lower case.
+		last = strings.ToLower(last)
+	case len(parts) > 1 && parts[0] == "ctrl":
+		// Bubble Tea reports control characters in lower case, e.g. "ctrl+x".
+		last = strings.ToLower(last)
+	}
+	parts[len(parts)-1] = last
+	return strings.Join(parts, "+"), nil
+}
+
+// applyToList sets the list navigation and filter bindings to the ones of
+// the key map, and the paging bindings to listKeys. The help of the list
+// is never shown, so its bindings are disabled.
+func (k keyMap) applyToList(lk *list.KeyMap) {
+	lk.CursorUp = k.Up
+	lk.CursorDown = k.Down
+	lk.Filter = k.Filter
+	lk.NextPage = listKeys["next page"]
+	lk.PrevPage = listKeys["previous page"]
+	lk.GoToStart = listKeys["first item"]
+	lk.GoToEnd = listKeys["last item"]
+	lk.ShowFullHelp = key.NewBinding(key.WithDisabled())
+	lk.CloseFullHelp = key.NewBinding(key.WithDisabled())
 }
 
 // ShortHelp returns keybindings to be shown in the mini help view. It's part
@@ -66,8 +288,12 @@ func (k keyMap) ShortHelp() []key.Binding {
 // key.Map interface.
 func (k keyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
-		{k.Add, k.Rename},
-		{k.Kill, k.Quit},
+		{k.Add, k.Rename, k.Worktree, k.Restore},
+		{k.Kill, k.Capture, k.Quit},
+		{k.Window, k.Move, k.Swap},
 		{k.Enter, k.Filter},
+		{k.Expand, k.Preview},
+		{k.Up, k.Down},
+		{k.Select, k.Sort, k.Group},
 	}
 }
@@ -0,0 +1,80 @@
+package tui
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+		err  bool
+	}{
+		{key: "x", want: "x"},
+		{key: "D", want: "D"},
+		{key: " ctrl+x ", want: "ctrl+x"},
+		{key: "Ctrl+X", want: "ctrl+x"},
+		{key: "alt+D", want: "alt+D"},
+		{key: "space", want: " "},
+		{key: "ctrl+Space", want: "ctrl+ "},
+		{key: "PgUp", want: "pgup"},
+		{key: "+", want: "+"},
+		{key: "", err: true},
+		{key: "ctrl+", err: true},
+		{key: "meta+x", err: true},
+	}
+	for _, tt := range tests {
+		got, err := normalizeKey(tt.key)
+		if tt.err {
+			if err == nil {
+				t.Errorf("normalizeKey(%q) = %q, want an error", tt.key, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("normalizeKey(%q): %v", tt.key, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("normalizeKey(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestNewKeyMap(t *testing.T) {
+	k, err := newKeyMap(map[string][]string{"kill": {"D", "ctrl+k"}})
+	if err != nil {
+		t.Fatalf("newKeyMap: %v", err)
+	}
+	if got, want := k.Kill.Keys(), []string{"D", "ctrl+k"}; !slices.Equal(got, want) {
+		t.Errorf("kill keys = %q, want %q", got, want)
+	}
+	if got := k.Kill.Help().Key; got != "D/ctrl+k" {
+		t.Errorf("kill help = %q, want %q", got, "D/ctrl+k")
+	}
+	if got, want := defaultKeyMap.Kill.Keys(), []string{"d"}; !slices.Equal(got, want) {
+		t.Errorf("default kill keys changed to %q, want %q", got, want)
+	}
+}
+
+func TestNewKeyMapErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides map[string][]string
+		want      string
+	}{
+		{"unknown action", map[string][]string{"jump": {"J"}}, `unknown key action "jump"`},
+		{"no keys", map[string][]string{"kill": {}}, `no keys bound to action "kill"`},
+		{"bad key", map[string][]string{"kill": {"hyper+k"}}, `unknown modifier "hyper"`},
+		{"conflict", map[string][]string{"kill": {"s"}}, `key "s" is bound to both "kill" and "start"`},
+		{"list key", map[string][]string{"start": {"pgdown"}}, `key "pgdown" is bound to both "next page" and "start"`},
+	}
+	for _, tt := range tests {
+		_, err := newKeyMap(tt.overrides)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: newKeyMap error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
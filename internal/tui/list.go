@@ -6,11 +6,11 @@ import (
 	"io"
 	"strings"
 	"time"
-	"unicode"
 
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/phanorcoll/muxie/internal/tmux"
 )
 
 const (
@@ -18,99 +18,176 @@ const (
 	defaultWidth = 40
 )
 
+// Styles of the list, built from the theme by theme.apply.
 var (
-	itemStyle              = lipgloss.NewStyle().PaddingLeft(4).Foreground(lipgloss.Color("#7C7C7E"))
-	selectedItemStyle      = lipgloss.NewStyle().PaddingLeft(2).Foreground(lipgloss.Color("#7fd1ae")).Bold(true)
-	paginationStyle        = list.DefaultStyles().PaginationStyle.PaddingLeft(4)
-	statusMessageStyle     = lipgloss.NewStyle().Foreground(lipgloss.AdaptiveColor{Light: "#04B575", Dark: "#04B575"}).Render
-	activeSessionStyle     = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#F2CC4A"))
-	activeSessionHelpStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#444341")).Render
-	errorStyle             = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF0000")).Render
+	itemStyle              lipgloss.Style
+	selectedItemStyle      lipgloss.Style
+	activeSessionStyle     lipgloss.Style
+	activeSessionHelpStyle func(...string) string
+	errorStyle             func(...string) string
+	groupStyle             lipgloss.Style
 )
 
 // session represents a tmux session in the TUI.
 // sessionName: the name of the session.
 // numWindows: the number of windows in the session.
+// attached: the number of clients attached to the session.
+// path: the working directory of the session.
+// created, activity: creation and last activity time of a running session.
+// lastAttached: the last time a client attached to the session, zero if never.
+// tags, source: the tags and source file of a config session, used for grouping.
 // activeSession: the currently active session, used for highlighting.
 // isFromConfig: true if the session is defined in the config file.
 // isRunning: true if the session is currently running.
+// isProject: true if the session is a git repository found in the project
+// roots, with path as its directory.
+// windows: the windows and panes of a running session, shown when it is expanded.
 type session struct {
 	sessionName     string
 	numWindows      int
+	attached        int
+	path            string
+	created         time.Time
+	activity        time.Time
+	lastAttached    time.Time
+	tags            []string
+	source          string
 	activeSession   string
 	isFromConfig    bool
 	isRunning       bool
+	isProject       bool
 	addSpacingUnder bool
+	windows         []tmux.WindowData
 }
 
 func (i session) SessionName() string { return i.sessionName }
 func (i session) FilterValue() string { return i.sessionName }
 
-type sessionDelegate struct{}
+// itemKey returns a stable identifier for a list item, used to keep the
+// cursor on the same item when the list is refreshed.
+func itemKey(item list.Item) string {
+	switch i := item.(type) {
+	case session:
+		return i.sessionName
+	case windowItem:
+		return fmt.Sprintf("%s:%d", i.sessionName, i.window.Index)
+	case paneItem:
+		return i.pane.ID
+	case groupItem:
+		return "group:" + i.name
+	case savedItem:
+		return "saved:" + i.session.Name
+	case snapshotItem:
+		return fmt.Sprintf("snapshot:%d", i.index)
+	}
+	return ""
+}
+
+// sessionDelegate renders the items of the session list.
+// selected: the sessions selected for bulk actions, shared with the Model.
+type sessionDelegate struct {
+	selected map[string]bool
+}
 
 func (d sessionDelegate) Height() int                             { return 1 }
 func (d sessionDelegate) Spacing() int                            { return 0 }
 func (d sessionDelegate) Update(_ tea.Msg, _ *list.Model) tea.Cmd { return nil }
 
 func (d sessionDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
-	i, ok := listItem.(session)
-	if !ok {
+	var desc string
+	switch i := listItem.(type) {
+	case session:
+		desc = renderSession(i)
+		if d.selected[i.sessionName] {
+			desc = icons.Marked + " " + desc
+		}
+	case windowItem:
+		desc = truncate(renderWindow(i), m.Width())
+		if i.addSpacingUnder {
+			desc += "\n"
+		}
+	case paneItem:
+		desc = truncate(renderPane(i), m.Width())
+		if i.addSpacingUnder {
+			desc += "\n"
+		}
+	case snapshotItem:
+		desc = truncate(renderSnapshot(i), m.Width()-itemStyle.GetPaddingLeft())
+	case savedItem:
+		desc = truncate(renderSaved(i), m.Width())
+		if d.selected[i.session.Name] {
+			desc = icons.Marked + " " + desc
+		}
+	case groupItem:
+		// Headers are never under the cursor and have their own padding.
+		fmt.Fprint(w, truncate(renderGroup(i), m.Width()))
 		return
+	default:
+		return
+	}
+
+	fn := itemStyle.Render
+	if index == m.Index() {
+		fn = func(s ...string) string {
+			return selectedItemStyle.Render(icons.Cursor + " " + strings.Join(s, " "))
+		}
 	}
 
+	fmt.Fprint(w, fn(desc))
+}
+
+// renderSession returns the line displayed for a session item.
+func renderSession(i session) string {
 	var icon string
 	if i.isFromConfig {
-		icon = "󰗼 "
+		icon = icons.Config + " "
 	} else {
-		icon = "󰆍 "
+		icon = icons.Running + " "
 	}
 
-	name := formatSessionName(i.sessionName)
-	activesession := formatSessionName(i.activeSession)
+	name := formatter.Format(i)
 
 	if !i.isRunning {
-		icon = "󰄜 "
+		icon = icons.Stopped + " "
+		if i.isProject {
+			icon = icons.Project + " "
+		}
 		name = activeSessionHelpStyle(name)
 	}
 
-	if name == activesession {
-		name = activeSessionStyle.Render(name + " " + activeSessionHelpStyle("  󰞓 active"))
+	if i.sessionName == i.activeSession {
+		name = activeSessionStyle.Render(name + " " + icons.ActiveMark + activeSessionHelpStyle("  "+icons.Active+" active"))
+	} else if i.attached > 0 {
+		name += activeSessionHelpStyle(fmt.Sprintf("  %s %d", icons.Attached, i.attached))
 	}
 
-	desc := fmt.Sprintf("-%d󱂬 - ", i.numWindows) + icon + name
+	windows := fmt.Sprintf("-%d%s - ", i.numWindows, icons.Windows)
+	if i.isProject {
+		// The windows of a project are only known once it is started.
+		windows = strings.Repeat(" ", lipgloss.Width(windows))
+	}
+	desc := windows + icon + name
 
 	// This just adds some separation between active/running sessions
 	// and the config sessions that have not been started yet
 	if i.addSpacingUnder {
 		desc += "\n"
 	}
-
-	fn := itemStyle.Render
-	if index == m.Index() {
-		fn = func(s ...string) string {
-			return selectedItemStyle.Render(" " + strings.Join(s, " "))
-		}
-	}
-
-	fmt.Fprint(w, fn(desc))
+	return desc
 }
 
-func formatSessionName(name string) string {
-	if len(name) == 0 {
-		return ""
-	}
-	name = strings.ToLower(name)
-	r := []rune(name)
-	r[0] = unicode.ToUpper(r[0])
-	return string(r)
+// truncate cuts s to at most width cells, preserving ANSI styling.
+func truncate(s string, width int) string {
+	return lipgloss.NewStyle().MaxWidth(width).Render(s)
 }
 
 // initList initializes and returns a new list.Model with default settings
 // for use in the TUI. The list is configured to hide the title, status bar,
 // and help, enables filtering, and disables quit keybindings.
-func initList() list.Model {
-	sessionList := list.New([]list.Item{}, sessionDelegate{}, defaultWidth, listHeight)
+func initList(selected map[string]bool) list.Model {
+	sessionList := list.New([]list.Item{}, sessionDelegate{selected: selected}, defaultWidth, listHeight)
 	sessionList.Styles.Title = titleStyle
+	sessionList.Styles.TitleBar = sessionList.Styles.TitleBar.PaddingLeft(0)
 	sessionList.Title = "Sessions"
 	sessionList.SetShowTitle(true)
 	sessionList.SetShowStatusBar(false)
@@ -2,64 +2,175 @@
 package tui
 
 import (
+	"fmt"
+	"log/slog"
+	"os"
+
 	"github.com/phanorcoll/muxie/internal/config"
-	"github.com/phanorcoll/muxie/internal/log"
+	"github.com/phanorcoll/muxie/internal/snapshot"
+	"github.com/phanorcoll/muxie/internal/state"
 
 	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/term"
 )
 
+// inputCharLimit is the maximum length of what is typed in a dialog, such
+// as a session name. Dialogs asking for a directory have no limit.
+const inputCharLimit = 50
+
 // statusData holds information about a status indicator in the TUI, including its icon,
 // action, color, and a human-readable action title.
 type statusData struct {
 	icon        string
 	action      string
-	color       string
+	color       lipgloss.TerminalColor
 	actionTitle string
+	detail      string
 }
 
 // Model represents the main state of the TUI application.
 // It contains configuration, session data, UI state, version, and input models.
 type Model struct {
-	version       string          // Application version
-	config        *config.Config  // Application configuration
-	statusData    statusData      // Current status indicator data
-	activeSession string          // Name of the currently active session
-	showInput     bool            // Whether the input field is visible
-	logger        log.Logger      // Logger for debugging
-	keys          keyMap          // Key bindings for the TUI
-	sessionList   list.Model      // List model for displaying sessions
-	help          help.Model      // Help model for displaying key bindings/help
-	sessionInput  textinput.Model // Text input model for session creation/renaming
+	version        string              // Application version
+	config         *config.Config      // Application configuration
+	statusData     statusData          // Current status indicator data
+	activeSession  string              // Name of the currently active session
+	showInput      bool                // Whether the input field is visible
+	logger         *slog.Logger        // Logger for debugging
+	keys           keyMap              // Key bindings for the TUI
+	sessionList    list.Model          // List model for displaying sessions
+	help           help.Model          // Help model for displaying key bindings/help
+	sessionInput   textinput.Model     // Text input model for session creation/renaming
+	pendingSelect  string              // Item to reselect once the list has been refiltered
+	sessions       []list.Item         // Sessions as last retrieved, before expansion into a tree
+	expanded       map[string]bool     // Sessions expanded into their windows and panes
+	showPreview    bool                // Whether the preview panel is visible
+	previewKey     string              // Item the preview was last requested for
+	preview        previewMsg          // Preview of the selected item
+	selected       map[string]bool     // Sessions selected for bulk actions
+	targets        []string            // Sessions the open dialog applies to
+	sortMode       string              // Order of the session list, one of sortModes
+	groupMode      string              // Grouping of the session list, one of groupModes
+	history        state.History       // Usage history, used to rank sessions by frecency
+	picker         picker              // Directory picker of the add dialog
+	projects       []string            // Git repositories found in the project roots
+	configSessions []config.Session    // Config sessions, with worktree sessions expanded
+	worktreeOf     map[string]string   // Config session of every worktree session
+	pendingCapture captureTarget       // What the open capture dialog applies to
+	pendingWindow  windowTarget        // Window or pane the open window dialog applies to
+	failure        *actionMsg          // Failed action shown in the error panel, nil if none
+	snapshots      []snapshot.Snapshot // Snapshots listed instead of the sessions, nil if none
+	restore        *snapshot.Snapshot  // Snapshot whose sessions are listed for restoring, nil if none
+	width          int                 // Width of the terminal
+	height         int                 // Height of the terminal
+}
+
+// settings are the TUI settings of a config, built by newSettings.
+type settings struct {
+	keys      keyMap
+	icons     iconSet
+	formatter itemFormatter
+	theme     theme
+}
+
+// newSettings builds the key bindings, icon set, item formatter and theme
+// configured in cfg, without applying them.
+// Returns an error if any of them is invalid.
+func newSettings(cfg *config.Config) (settings, error) {
+	keys, err := newKeyMap(cfg.Keys)
+	if err != nil {
+		return settings{}, fmt.Errorf("invalid key bindings: %w", err)
+	}
+	icons, err := newIconSet(cfg.Icons)
+	if err != nil {
+		return settings{}, fmt.Errorf("invalid icons: %w", err)
+	}
+	formatter, err := newItemFormatter(cfg.Display)
+	if err != nil {
+		return settings{}, fmt.Errorf("invalid display: %w", err)
+	}
+	theme, err := newTheme(cfg.Theme)
+	if err != nil {
+		return settings{}, fmt.Errorf("invalid theme: %w", err)
+	}
+	return settings{keys: keys, icons: icons, formatter: formatter, theme: theme}, nil
+}
+
+// ValidateConfig checks the TUI settings of cfg: the key bindings, icons,
+// display format and theme. Unlike NewModel, it has no side effects.
+func ValidateConfig(cfg *config.Config) error {
+	_, err := newSettings(cfg)
+	return err
 }
 
 // NewModel creates and returns a new Model instance for the TUI application.
 // It initializes the session list, status data, key bindings, help model, and session input field.
-func NewModel(cfg *config.Config, logger log.Logger, version string) Model {
+// Returns an error if the TUI settings in the config are invalid, as reported by ValidateConfig.
+func NewModel(cfg *config.Config, logger *slog.Logger, version string) (Model, error) {
+	s, err := newSettings(cfg)
+	if err != nil {
+		return Model{}, err
+	}
+	keys := s.keys
+	icons, formatter = s.icons, s.formatter
+	s.theme.apply()
 	newSessionInput := textinput.New()
-	newSessionInput.CharLimit = 50
+	newSessionInput.CharLimit = inputCharLimit
 	newSessionInput.Width = 20
-	sl := initList()
-	return Model{
+	selected := make(map[string]bool)
+	sl := initList(selected)
+	hm := help.New()
+	hm.ShortSeparator = " " + icons.Bullet + " "
+	keys.applyToList(&sl.KeyMap)
+	// Start with the current terminal size, until the first WindowSizeMsg arrives.
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		width, height = 80, 20
+	}
+	// The sort and group modes are restored from the previous run.
+	ui, err := state.LoadUI()
+	if err != nil {
+		logger.Warn("could not load UI state", "err", err)
+	}
+	history, err := state.LoadHistory()
+	if err != nil {
+		logger.Warn("could not load history", "err", err)
+	}
+	m := Model{
 		version: version,
 		config:  cfg,
 		logger:  logger,
 		statusData: statusData{
-			icon:   "",
+			icon:   icons.Idle,
 			action: "",
-			color:  "#FFF7DB",
+			color:  statusColor,
 		},
-		sessionList:  sl,
-		keys:         defaultKeyMap,
-		help:         help.New(),
-		sessionInput: newSessionInput,
+		sessionList:    sl,
+		keys:           keys,
+		help:           hm,
+		sessionInput:   newSessionInput,
+		expanded:       make(map[string]bool),
+		selected:       selected,
+		showPreview:    true,
+		sortMode:       validMode(sortModes, ui.Sort),
+		groupMode:      validMode(groupModes, ui.Group),
+		history:        history,
+		configSessions: cfg.Sessions,
+		width:          width,
+		height:         height,
 	}
+	m.setTitle()
+	m.resize()
+	return m, nil
 }
 
 // Init is part of the Bubble Tea Model interface and initializes the program.
 // It returns an initial command to run, or nil if there is none.
 func (m Model) Init() tea.Cmd {
-	return getSessionsCmd(m.config)
+	// The sessions are listed once the worktrees are expanded.
+	return tea.Batch(expandWorktreesCmd(m.config), refreshCmd(m.config.Refresh()), loadProjectsCmd(m.config), checkVersionCmd())
 }
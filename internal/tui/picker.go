@@ -0,0 +1,132 @@
+// Package tui contains terminal user interface commands and related functionality.
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/phanorcoll/muxie/internal/config"
+	"github.com/phanorcoll/muxie/internal/project"
+)
+
+// maxPickerRows is the largest number of directories listed under the input
+// of the add dialog.
+const maxPickerRows = 8
+
+// Keys moving the cursor of the directory picker. Letters are typed into the
+// input, so only arrows and control keys move the cursor.
+var (
+	pickerUp   = key.NewBinding(key.WithKeys("up", "ctrl+p"))
+	pickerDown = key.NewBinding(key.WithKeys("down", "ctrl+n"))
+)
+
+// pickerMsg carries the directories offered by the add dialog.
+type pickerMsg struct {
+	dirs []string
+	err  error
+}
+
+// pickerCmd returns a command that lists the directories configured in cfg.
+// Returns nil if the picker is not configured.
+func pickerCmd(cfg config.Picker) tea.Cmd {
+	if len(cfg.Roots) == 0 && !cfg.Zoxide {
+		return nil
+	}
+	return func() tea.Msg {
+		dirs, err := project.Dirs(cfg.Roots, cfg.Zoxide)
+		return pickerMsg{dirs: dirs, err: err}
+	}
+}
+
+// picker holds the state of the directory picker of the add dialog.
+// dirs: every directory offered.
+// matches: the directories fuzzy matching query, best match first.
+// query: the text typed in the input.
+// index: the row under the cursor; the row after the matches creates a
+// session named after the query, without a directory.
+type picker struct {
+	dirs    []string
+	matches []string
+	query   string
+	index   int
+}
+
+// filter updates the matches for the given query and moves the cursor to
+// the best match.
+func (p *picker) filter(query string) {
+	p.query = query
+	p.index = 0
+	if query == "" {
+		p.matches = p.dirs
+		return
+	}
+	targets := make([]string, len(p.dirs))
+	for i, dir := range p.dirs {
+		targets[i] = shortenPath(dir)
+	}
+	p.matches = nil
+	for _, rank := range list.DefaultFilter(query, targets) {
+		p.matches = append(p.matches, p.dirs[rank.Index])
+	}
+}
+
+// rows returns the number of matches shown when at most maxRows fit.
+func (p picker) rows(maxRows int) int {
+	return max(0, min(len(p.matches), maxRows, maxPickerRows))
+}
+
+// move moves the cursor by delta rows, wrapping around, when maxRows
+// matches fit in the dialog.
+func (p *picker) move(delta, maxRows int) {
+	n := p.rows(maxRows)
+	if p.query != "" {
+		n++
+	}
+	if n == 0 {
+		return
+	}
+	p.index = ((p.index+delta)%n + n) % n
+}
+
+// choice returns the name and directory of the session to create: the
+// directory under the cursor, or the query with no directory.
+func (p picker) choice(maxRows int) (name, dir string) {
+	if p.index < p.rows(maxRows) {
+		dir = p.matches[p.index]
+		return project.SessionName(dir), dir
+	}
+	return p.query, ""
+}
+
+// view renders the matches that fit in maxRows, followed by the row
+// creating a session named after the query. Rows are cut to width.
+func (p picker) view(maxRows, width int) string {
+	var rows []string
+	line := func(i int, s string) {
+		if i == p.index {
+			s = base.Foreground(addColor).Render(icons.Cursor + " " + s)
+		} else {
+			s = inputHelpStyle.Render("  " + s)
+		}
+		rows = append(rows, truncate(s, width))
+	}
+	for i, dir := range p.matches[:p.rows(maxRows)] {
+		line(i, shortenPath(dir))
+	}
+	if p.query != "" {
+		line(p.rows(maxRows), fmt.Sprintf("%s new session %q", icons.Add, p.query))
+	}
+	return strings.Join(rows, "\n")
+}
+
+// pickerRows returns the number of picker rows that fit in the add dialog
+// for the current layout.
+func (m Model) pickerRows() int {
+	l := m.layout()
+	// Leave room for the title, input and hint of the dialog, its border
+	// and padding, and the row creating a session from the query.
+	return l.height - l.headerHeight() - 10
+}
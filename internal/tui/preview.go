@@ -0,0 +1,168 @@
+// Package tui contains terminal user interface commands and related functionality.
+package tui
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/phanorcoll/muxie/internal/config"
+	"github.com/phanorcoll/muxie/internal/tmux"
+)
+
+const schematicPaneRow = 3
+
+// sessionTime is the layout of the times shown in the header of the preview
+// of a running session.
+const sessionTime = "Jan 2 15:04"
+
+// Styles of the preview, built from the theme by theme.apply.
+var (
+	previewStyle       lipgloss.Style
+	schematicWinStyle  lipgloss.Style
+	schematicPaneStyle lipgloss.Style
+)
+
+// ansiSequence matches CSI escape sequences such as colors in captured panes.
+var ansiSequence = regexp.MustCompile(`\x1b\[[0-9;?]*[a-zA-Z]`)
+
+// previewMsg carries the rendered preview for the list item identified by key.
+// tail is true if the end of the content is the most relevant part, as for
+// captured panes. header is shown above the content and always kept.
+type previewMsg struct {
+	key     string
+	header  string
+	content string
+	tail    bool
+}
+
+// previewCmd returns a command that renders the preview of the given list item.
+// Running sessions, windows and panes are captured from tmux, while sessions
+// that have not been started yet are drawn from their configuration.
+// configSessions are the config sessions with worktree sessions expanded, and
+// width is the width available to draw the layout of configured sessions.
+func previewCmd(cfg *config.Config, configSessions []config.Session, item list.Item, width int) tea.Cmd {
+	key := itemKey(item)
+	var target, header string
+	switch i := item.(type) {
+	case session:
+		if i.isProject {
+			dir := i.path
+			return func() tea.Msg {
+				s, err := projectSession(cfg, dir)
+				if err != nil {
+					return previewMsg{key: key, content: errorStyle(err.Error())}
+				}
+				return previewMsg{key: key, content: renderSchematic(s, width)}
+			}
+		}
+		if !i.isRunning {
+			for _, s := range configSessions {
+				if s.Name == i.sessionName {
+					content := renderSchematic(s, width)
+					return func() tea.Msg {
+						return previewMsg{key: key, content: content}
+					}
+				}
+			}
+			return nil
+		}
+		target = i.sessionName
+		header = sessionHeader(i)
+	case windowItem:
+		target = fmt.Sprintf("%s:%d", i.sessionName, i.window.Index)
+	case paneItem:
+		target = i.pane.ID
+	case savedItem:
+		content := renderSchematic(savedSchematic(i.session), width)
+		return func() tea.Msg {
+			return previewMsg{key: key, content: content}
+		}
+	case snapshotItem:
+		content := renderSnapshotSessions(i.snapshot)
+		return func() tea.Msg {
+			return previewMsg{key: key, content: content}
+		}
+	default:
+		return nil
+	}
+	return func() tea.Msg {
+		content, err := tmux.CapturePane(target)
+		if err != nil {
+			content = errorStyle(fmt.Sprintf("could not capture %s: %v", target, err))
+		} else if noColor {
+			content = ansiSequence.ReplaceAllString(content, "")
+		}
+		return previewMsg{key: key, header: header, content: content, tail: true}
+	}
+}
+
+// sessionHeader returns the header of the preview of the running session s:
+// when it was created and last active, and its directory.
+func sessionHeader(s session) string {
+	times := fmt.Sprintf("created %s %s active %s", s.created.Format(sessionTime), icons.Bullet, s.activity.Format(sessionTime))
+	return versionStyle(times) + "\n" + activeSessionHelpStyle(shortenPath(s.path))
+}
+
+// renderPreview fits the header and content of p into a panel of the given
+// size. Trailing blank lines are dropped and, if tail is true, only the last
+// lines that fit under the header are kept, as that is where the cursor of
+// a shell usually is.
+func renderPreview(p previewMsg, width, height int) string {
+	lines := strings.Split(p.content, "\n")
+	for len(lines) > 0 && strings.TrimSpace(ansiSequence.ReplaceAllString(lines[len(lines)-1], "")) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if p.header != "" {
+		// The header is followed by a blank line.
+		lines = append(strings.Split(p.header+"\n", "\n"), lines...)
+	}
+	if p.tail && len(lines) > height {
+		headerLines := 0
+		if p.header != "" {
+			headerLines = lipgloss.Height(p.header) + 1
+		}
+		lines = append(lines[:min(headerLines, height)], lines[len(lines)-max(0, height-headerLines):]...)
+	}
+	body := lipgloss.NewStyle().MaxWidth(width).MaxHeight(height).Render(strings.Join(lines, "\n"))
+	return previewStyle.Width(width).Height(height).Render(body)
+}
+
+// renderSchematic draws the windows of a configured session and the layout
+// of their panes in the given width, for sessions that have not been started yet.
+func renderSchematic(s config.Session, width int) string {
+	inner := width - 2
+	var windows []string
+	for _, w := range s.Windows {
+		var panes []string
+		for _, p := range w.Panes {
+			panes = append(panes, p.Command)
+		}
+		if len(panes) == 0 {
+			panes = []string{""}
+		}
+
+		var body string
+		if w.Layout == "vertical" {
+			var rows []string
+			for _, p := range panes {
+				rows = append(rows, schematicPaneStyle.Width(inner-2).Height(schematicPaneRow-2).Render(p))
+			}
+			body = lipgloss.JoinVertical(lipgloss.Left, rows...)
+		} else {
+			paneWidth := inner/len(panes) - 2
+			var cols []string
+			for _, p := range panes {
+				cols = append(cols, schematicPaneStyle.Width(paneWidth).Height(schematicPaneRow-2).Render(p))
+			}
+			body = lipgloss.JoinHorizontal(lipgloss.Top, cols...)
+		}
+		title := titleStyle.Render(w.Name)
+		windows = append(windows, lipgloss.JoinVertical(lipgloss.Left, title, schematicWinStyle.Render(body)))
+	}
+	header := versionStyle(fmt.Sprintf("not started %s %s", icons.Bullet, shortenPath(s.Directory)))
+	return lipgloss.JoinVertical(lipgloss.Left, append([]string{header}, windows...)...)
+}
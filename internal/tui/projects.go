@@ -0,0 +1,107 @@
+// Package tui contains terminal user interface commands and related functionality.
+package tui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/phanorcoll/muxie/internal/config"
+	"github.com/phanorcoll/muxie/internal/project"
+	"github.com/phanorcoll/muxie/internal/state"
+)
+
+// projectsTTL is how long the cached repositories are used before the
+// project roots are scanned again.
+const projectsTTL = 5 * time.Minute
+
+// projectsMsg carries the git repositories found in the project roots.
+// stale is true if they come from an outdated cache and the roots have to
+// be scanned again.
+type projectsMsg struct {
+	dirs  []string
+	stale bool
+	err   error
+}
+
+// projectsKey identifies the settings the project roots are scanned with,
+// so the cache is not used once they change.
+func projectsKey(cfg *config.Config) string {
+	return fmt.Sprint(cfg.ProjectRoots, cfg.ProjectDepth, cfg.ProjectIgnore)
+}
+
+// loadProjectsCmd returns a command that reads the cached repositories.
+// Returns nil if no project roots are configured.
+func loadProjectsCmd(cfg *config.Config) tea.Cmd {
+	if len(cfg.ProjectRoots) == 0 {
+		return nil
+	}
+	return func() tea.Msg {
+		p, err := state.LoadProjects()
+		if err != nil || p.Key != projectsKey(cfg) {
+			return projectsMsg{stale: true}
+		}
+		return projectsMsg{dirs: p.Dirs, stale: time.Since(p.Scanned) > projectsTTL}
+	}
+}
+
+// scanProjectsCmd returns a command that scans the project roots for
+// repositories and caches them.
+func scanProjectsCmd(cfg *config.Config) tea.Cmd {
+	return func() tea.Msg {
+		dirs, err := project.Discover(cfg.ProjectRoots, cfg.ProjectDepth, cfg.ProjectIgnore)
+		if err != nil {
+			return projectsMsg{err: fmt.Errorf("could not scan project roots: %w", err)}
+		}
+		p := state.Projects{Key: projectsKey(cfg), Scanned: time.Now(), Dirs: dirs}
+		if err := state.SaveProjects(p); err != nil {
+			return projectsMsg{dirs: dirs, err: err}
+		}
+		return projectsMsg{dirs: dirs}
+	}
+}
+
+// withProjects returns the sessions followed by a session item for every
+// repository in dirs, unless a session with the same name already exists.
+func withProjects(sessions []list.Item, dirs []string, activeSession string) []list.Item {
+	if len(dirs) == 0 {
+		return sessions
+	}
+	names := make(map[string]bool)
+	for _, item := range sessions {
+		if s, ok := item.(session); ok {
+			names[s.sessionName] = true
+		}
+	}
+	items := append([]list.Item(nil), sessions...)
+	for _, dir := range dirs {
+		name := project.SessionName(dir)
+		if names[name] {
+			continue
+		}
+		names[name] = true
+		items = append(items, session{
+			sessionName:   name,
+			path:          dir,
+			activeSession: activeSession,
+			isProject:     true,
+		})
+	}
+	return items
+}
+
+// projectSession returns the session to start for the repository in dir:
+// the one described by its .muxie.yml file, or one with the configured
+// project windows otherwise.
+func projectSession(cfg *config.Config, dir string) (config.Session, error) {
+	s, ok, err := config.LoadProject(dir)
+	if err != nil || ok {
+		return s, err
+	}
+	windows := cfg.ProjectWindows
+	if len(windows) == 0 {
+		windows = []config.Window{{Name: "main"}}
+	}
+	return config.Session{Directory: dir, Windows: windows}, nil
+}
@@ -0,0 +1,210 @@
+// Package tui contains terminal user interface commands and related functionality.
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/phanorcoll/muxie/internal/config"
+	"github.com/phanorcoll/muxie/internal/snapshot"
+)
+
+// snapshotTime is the layout of the time a snapshot was saved in the list.
+const snapshotTime = "Jan 2 15:04"
+
+// snapshotItem is a saved snapshot, listed while browsing the snapshots.
+// index: the position of the snapshot, 0 for the last one.
+// stopped: the number of its sessions that are not running.
+type snapshotItem struct {
+	index    int
+	snapshot snapshot.Snapshot
+	stopped  int
+}
+
+func (i snapshotItem) FilterValue() string { return i.snapshot.Saved.Format(snapshotTime) }
+
+// renderSnapshot returns the line displayed for a snapshot.
+func renderSnapshot(i snapshotItem) string {
+	kind := "saved"
+	if i.snapshot.Auto {
+		kind = "auto"
+	}
+	return fmt.Sprintf("%s %s%s", i.snapshot.Saved.Format(snapshotTime), kind,
+		activeSessionHelpStyle(fmt.Sprintf("  %d sessions, %d stopped", len(i.snapshot.Sessions), i.stopped)))
+}
+
+// renderSnapshotSessions lists the sessions of a snapshot in the preview.
+func renderSnapshotSessions(s snapshot.Snapshot) string {
+	lines := []string{versionStyle(fmt.Sprintf("%d sessions %s saved %s", len(s.Sessions), icons.Bullet, s.Saved.Format(snapshotTime)))}
+	for _, ss := range s.Sessions {
+		lines = append(lines, fmt.Sprintf("-%d%s - %s%s", len(ss.Windows), icons.Windows, ss.Name,
+			activeSessionHelpStyle("  "+shortenPath(ss.Path))))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// savedItem is a session of a snapshot that is not running, listed while
+// the snapshot is open for restoring.
+type savedItem struct {
+	session snapshot.Session
+}
+
+func (i savedItem) FilterValue() string { return i.session.Name }
+
+// renderSaved returns the line displayed for a saved session.
+func renderSaved(i savedItem) string {
+	return fmt.Sprintf("-%d%s - %s %s%s", len(i.session.Windows), icons.Windows, icons.Stopped, i.session.Name,
+		activeSessionHelpStyle("  "+shortenPath(i.session.Path)))
+}
+
+// snapshotsMsg carries the saved snapshots, to browse them.
+type snapshotsMsg struct {
+	snapshots []snapshot.Snapshot
+	err       error
+}
+
+// loadSnapshotsCmd returns a command that reads the saved snapshots.
+func loadSnapshotsCmd() tea.Cmd {
+	return func() tea.Msg {
+		s, err := snapshot.List()
+		return snapshotsMsg{snapshots: s, err: err}
+	}
+}
+
+// stoppedSessions returns the sessions of s that are not running.
+func (m Model) stoppedSessions(s snapshot.Snapshot) []snapshot.Session {
+	var sessions []snapshot.Session
+	for _, ss := range s.Sessions {
+		if !m.isRunning(ss.Name) {
+			sessions = append(sessions, ss)
+		}
+	}
+	return sessions
+}
+
+// restoreItems returns an item for every session of the open snapshot that
+// is not running or, if none is open, for every snapshot being browsed.
+func (m Model) restoreItems() []list.Item {
+	var items []list.Item
+	if m.restore != nil {
+		for _, s := range m.stoppedSessions(*m.restore) {
+			items = append(items, savedItem{session: s})
+		}
+		return items
+	}
+	for i, s := range m.snapshots {
+		items = append(items, snapshotItem{index: i, snapshot: s, stopped: len(m.stoppedSessions(s))})
+	}
+	return items
+}
+
+// openSnapshots replaces the sessions with the saved snapshots, for one to
+// be opened. The snapshot is opened right away if it is the only one.
+// Returns a status message instead if there is nothing to restore.
+func (m *Model) openSnapshots(snaps []snapshot.Snapshot) tea.Cmd {
+	switch len(snaps) {
+	case 0:
+		return m.sessionList.NewStatusMessage(errorMessage("no snapshot saved"))
+	case 1:
+		return m.openRestore(snaps[0])
+	}
+	m.snapshots = snaps
+	m.restore = nil
+	return m.showRestoreList(0)
+}
+
+// openRestore replaces the sessions or the snapshots with the sessions of s
+// that are not running, for them to be restored. Returns a status message
+// instead if there is nothing to restore.
+func (m *Model) openRestore(s snapshot.Snapshot) tea.Cmd {
+	if len(m.stoppedSessions(s)) == 0 {
+		return m.sessionList.NewStatusMessage(errorMessage("nothing to restore"))
+	}
+	m.restore = &s
+	return m.showRestoreList(0)
+}
+
+// backFromRestore goes back from an open snapshot to the list of snapshots,
+// or to the sessions if there is only one.
+func (m *Model) backFromRestore() tea.Cmd {
+	if m.restore == nil || len(m.snapshots) == 0 {
+		return m.closeRestore()
+	}
+	index := 0
+	for i, s := range m.snapshots {
+		if s.Saved.Equal(m.restore.Saved) {
+			index = i
+		}
+	}
+	m.restore = nil
+	return m.showRestoreList(index)
+}
+
+// showRestoreList sets the snapshots or the sessions of the open snapshot
+// on the list, with the cursor on the item at index.
+func (m *Model) showRestoreList(index int) tea.Cmd {
+	clear(m.selected)
+	m.sessionList.ResetFilter()
+	m.setTitle()
+	cmd := m.setItems()
+	m.sessionList.Select(index)
+	return cmd
+}
+
+// closeRestore goes back from the snapshots to the sessions.
+func (m *Model) closeRestore() tea.Cmd {
+	m.snapshots = nil
+	m.restore = nil
+	clear(m.selected)
+	m.sessionList.ResetFilter()
+	m.setTitle()
+	return m.setItems()
+}
+
+// restoreTitle returns the title of the list while browsing the snapshots.
+func (m Model) restoreTitle() string {
+	if m.restore == nil {
+		return fmt.Sprintf("Restore %s %d snapshots", icons.Bullet, len(m.snapshots))
+	}
+	return "Restore " + icons.Bullet + " saved " + m.restore.Saved.Format(snapshotTime)
+}
+
+// restoreCmd returns a command restoring the selected saved sessions shown
+// by the filter, or the one under the cursor if none is, then closing the
+// snapshots.
+func (m *Model) restoreCmd() tea.Cmd {
+	var targets []string
+	for _, item := range m.sessionList.VisibleItems() {
+		if i, ok := item.(savedItem); ok && m.selected[i.session.Name] {
+			targets = append(targets, i.session.Name)
+		}
+	}
+	if len(targets) == 0 {
+		i, ok := m.sessionList.SelectedItem().(savedItem)
+		if !ok {
+			return nil
+		}
+		targets = []string{i.session.Name}
+	}
+	snap := *m.restore
+	return tea.Batch(m.closeRestore(), actionCmd("restore", "restored", targets, false, func(name string) error {
+		s, _ := snap.Find(name)
+		return snapshot.Restore(s)
+	}))
+}
+
+// savedSchematic returns the saved session s as a config session, for its
+// windows and commands to be drawn by renderSchematic.
+func savedSchematic(s snapshot.Session) config.Session {
+	cs := config.Session{Name: s.Name, Directory: s.Path}
+	for _, w := range s.Windows {
+		cw := config.Window{Name: w.Name}
+		for _, p := range w.Panes {
+			cw.Panes = append(cw.Panes, config.Pane{Command: p.Command, Directory: p.Path})
+		}
+		cs.Windows = append(cs.Windows, cw)
+	}
+	return cs
+}
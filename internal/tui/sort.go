@@ -0,0 +1,224 @@
+// Package tui contains terminal user interface commands and related functionality.
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/phanorcoll/muxie/internal/state"
+)
+
+// sortModes are the orders the session list can be sorted in, in the order
+// the sort key cycles through them. The first one is the default.
+//   - frecency: as status, with sessions used most often and most recently
+//     from muxie first
+//   - status: active session, then running sessions, then config sessions
+//   - name: alphabetical, ignoring case
+//   - recent: most recently attached first
+//   - created: most recently created first
+//   - config: order of the config file, then other running sessions
+var sortModes = []string{"frecency", "status", "name", "recent", "created", "config"}
+
+// groupModes are the ways sessions can be grouped under headers, in the
+// order the group key cycles through them. The first one is the default.
+//   - none: no grouping
+//   - tag: by the first tag of config sessions
+//   - source: by the config file sessions are defined in, with discovered
+//     projects in their own group
+var groupModes = []string{"none", "tag", "source"}
+
+// otherGroup is the group of sessions without a tag or source file.
+const otherGroup = "other"
+
+// groupItem is the header of a group of sessions in the list.
+// name: the tag or source file shared by the sessions of the group.
+// count: the number of sessions in the group.
+type groupItem struct {
+	name  string
+	count int
+}
+
+// FilterValue returns an empty string so headers are hidden while filtering.
+func (i groupItem) FilterValue() string { return "" }
+
+// renderGroup returns the line displayed for a group header.
+func renderGroup(i groupItem) string {
+	return groupStyle.Render(fmt.Sprintf("%s (%d)", i.name, i.count))
+}
+
+// nextMode returns the mode following current in modes, wrapping around.
+func nextMode(modes []string, current string) string {
+	for i, m := range modes {
+		if m == current {
+			return modes[(i+1)%len(modes)]
+		}
+	}
+	return modes[0]
+}
+
+// validMode returns mode if it is one of modes, or the default mode otherwise.
+func validMode(modes []string, mode string) string {
+	for _, m := range modes {
+		if m == mode {
+			return mode
+		}
+	}
+	return modes[0]
+}
+
+// arrangeSessions sorts the sessions with sortBy and, unless groupBy is
+// "none", places them under group headers. sessions must be in the order
+// returned by getSessionsCmd. history ranks the sessions in frecency mode.
+func arrangeSessions(sessions []list.Item, activeSession, sortBy, groupBy string, history state.History) []list.Item {
+	sorted := sortSessions(sessions, activeSession, sortBy, history)
+	if groupBy == "none" {
+		return sorted
+	}
+	return groupSessions(sorted, groupBy)
+}
+
+// sortSessions returns a copy of sessions sorted with the given mode.
+// Sessions comparing equal keep their config order.
+func sortSessions(sessions []list.Item, activeSession, sortBy string, history state.History) []list.Item {
+	if sortBy == "status" {
+		return moveActiveSessionToTop(sessions, activeSession)
+	}
+	sorted := make([]list.Item, len(sessions))
+	copy(sorted, sessions)
+
+	var less func(a, b session) bool
+	switch sortBy {
+	case "frecency":
+		now := time.Now()
+		less = func(a, b session) bool {
+			return history.Frecency(a.sessionName, now) > history.Frecency(b.sessionName, now)
+		}
+	case "name":
+		less = func(a, b session) bool {
+			return strings.ToLower(a.sessionName) < strings.ToLower(b.sessionName)
+		}
+	case "recent":
+		less = func(a, b session) bool {
+			if a.isRunning != b.isRunning {
+				return a.isRunning
+			}
+			return a.lastAttached.After(b.lastAttached)
+		}
+	case "created":
+		less = func(a, b session) bool {
+			if a.isRunning != b.isRunning {
+				return a.isRunning
+			}
+			return a.created.After(b.created)
+		}
+	default:
+		// Sessions are already in config order.
+		return sorted
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, aok := sorted[i].(session)
+		b, bok := sorted[j].(session)
+		return aok && bok && less(a, b)
+	})
+	if sortBy == "frecency" {
+		return moveActiveSessionToTop(sorted, activeSession)
+	}
+	return sorted
+}
+
+// groupSessions places the sorted sessions under a header per group, with
+// groups in alphabetical order and sessions without a group last.
+func groupSessions(sorted []list.Item, groupBy string) []list.Item {
+	groups := make(map[string][]list.Item)
+	for _, item := range sorted {
+		s, ok := item.(session)
+		if !ok {
+			continue
+		}
+		// Headers already separate the sessions.
+		s.addSpacingUnder = false
+		name := sessionGroup(s, groupBy)
+		groups[name] = append(groups[name], s)
+	}
+
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		if name != otherGroup {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if _, ok := groups[otherGroup]; ok {
+		names = append(names, otherGroup)
+	}
+
+	var items []list.Item
+	for _, name := range names {
+		items = append(items, groupItem{name: name, count: len(groups[name])})
+		items = append(items, groups[name]...)
+	}
+	return items
+}
+
+// sessionGroup returns the name of the group s belongs to with the given mode.
+func sessionGroup(s session, groupBy string) string {
+	switch groupBy {
+	case "tag":
+		if len(s.tags) > 0 {
+			return s.tags[0]
+		}
+	case "source":
+		if s.isProject {
+			return "projects"
+		}
+		if s.source != "" {
+			return filepath.Base(s.source)
+		}
+	}
+	return otherGroup
+}
+
+// skipGroupHeader moves the cursor off a group header, which cannot be
+// selected, continuing in the direction it was moving from prevIndex.
+func (m *Model) skipGroupHeader(prevIndex int) {
+	if _, ok := m.sessionList.SelectedItem().(groupItem); !ok {
+		return
+	}
+	if m.sessionList.Index() < prevIndex {
+		m.sessionList.CursorUp()
+	} else {
+		m.sessionList.CursorDown()
+	}
+	// The header is the first item of the list, go the other way.
+	if _, ok := m.sessionList.SelectedItem().(groupItem); ok {
+		m.sessionList.CursorDown()
+	}
+}
+
+// setTitle shows the sort and group modes in the title of the list when
+// they differ from the defaults.
+func (m *Model) setTitle() {
+	if m.snapshots != nil || m.restore != nil {
+		m.sessionList.Title = m.restoreTitle()
+		return
+	}
+	title := "Sessions"
+	if m.sortMode != sortModes[0] {
+		title += " " + icons.Bullet + " " + m.sortMode
+	}
+	if m.groupMode != groupModes[0] {
+		title += " " + icons.Bullet + " by " + m.groupMode
+	}
+	m.sessionList.Title = title
+}
+
+// saveUI persists the sort and group modes for the next run.
+func (m Model) saveUI() {
+	if err := state.SaveUI(state.UI{Sort: m.sortMode, Group: m.groupMode}); err != nil {
+		m.logger.Warn("could not save UI state", "err", err)
+	}
+}
@@ -0,0 +1,218 @@
+// Package tui contains terminal user interface commands and related functionality.
+package tui
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/phanorcoll/muxie/internal/config"
+)
+
+// defaultTheme is the theme used when none is set in the config file.
+// It picks the light or dark colors depending on the terminal background.
+const defaultTheme = "auto"
+
+// theme holds the colors used to render the TUI.
+type theme struct {
+	Text     lipgloss.TerminalColor // Titles and regular text
+	Subtle   lipgloss.TerminalColor // Version, dividers and hints
+	Item     lipgloss.TerminalColor // Sessions in the list
+	Selected lipgloss.TerminalColor // Session under the cursor
+	Active   lipgloss.TerminalColor // Currently active session, window or pane
+	Dim      lipgloss.TerminalColor // Sessions that are not running and extra details
+	Error    lipgloss.TerminalColor // Error messages
+	Border   lipgloss.TerminalColor // Borders of the main and preview panels
+	Status   lipgloss.TerminalColor // Status icon when no action is in progress
+	Add      lipgloss.TerminalColor // Add session dialog
+	Rename   lipgloss.TerminalColor // Rename session dialog
+	Kill     lipgloss.TerminalColor // Kill session dialog
+}
+
+var (
+	darkTheme = theme{
+		Text:     lipgloss.Color("#EEEEEE"),
+		Subtle:   lipgloss.Color("#72726F"),
+		Item:     lipgloss.Color("#7C7C7E"),
+		Selected: lipgloss.Color("#7fd1ae"),
+		Active:   lipgloss.Color("#F2CC4A"),
+		Dim:      lipgloss.Color("#444341"),
+		Error:    lipgloss.Color("#FF0000"),
+		Border:   lipgloss.Color("#5a5a5a"),
+		Status:   lipgloss.Color("#FFF7DB"),
+		Add:      lipgloss.Color("#37A1C5"),
+		Rename:   lipgloss.Color("#BAC537"),
+		Kill:     lipgloss.Color("#C53770"),
+	}
+	lightTheme = theme{
+		Text:     lipgloss.Color("#1F1F1F"),
+		Subtle:   lipgloss.Color("#8A8A8A"),
+		Item:     lipgloss.Color("#5A5A5A"),
+		Selected: lipgloss.Color("#0E7C5A"),
+		Active:   lipgloss.Color("#A66F00"),
+		Dim:      lipgloss.Color("#A8A8A8"),
+		Error:    lipgloss.Color("#D70000"),
+		Border:   lipgloss.Color("#BDBDBD"),
+		Status:   lipgloss.Color("#333333"),
+		Add:      lipgloss.Color("#1D6FA5"),
+		Rename:   lipgloss.Color("#6B7A00"),
+		Kill:     lipgloss.Color("#B0245A"),
+	}
+	// highContrastTheme only uses the bright ANSI colors, which the
+	// terminal color scheme keeps readable on its own background.
+	highContrastTheme = theme{
+		Text:     lipgloss.Color("15"),
+		Subtle:   lipgloss.Color("7"),
+		Item:     lipgloss.Color("15"),
+		Selected: lipgloss.Color("14"),
+		Active:   lipgloss.Color("11"),
+		Dim:      lipgloss.Color("7"),
+		Error:    lipgloss.Color("9"),
+		Border:   lipgloss.Color("15"),
+		Status:   lipgloss.Color("15"),
+		Add:      lipgloss.Color("12"),
+		Rename:   lipgloss.Color("11"),
+		Kill:     lipgloss.Color("13"),
+	}
+
+	themes = map[string]theme{
+		"auto":          adaptiveTheme(lightTheme, darkTheme),
+		"dark":          darkTheme,
+		"light":         lightTheme,
+		"high-contrast": highContrastTheme,
+	}
+
+	// hexColor matches colors such as "#7fd1ae".
+	hexColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+)
+
+// noColor is true if the NO_COLOR environment variable is set, see https://no-color.org.
+var noColor = os.Getenv("NO_COLOR") != ""
+
+// adaptiveTheme combines a light and a dark theme into one that picks
+// the colors matching the terminal background.
+func adaptiveTheme(light, dark theme) theme {
+	t := theme{}
+	lc, dc, tc := light.colors(), dark.colors(), t.colors()
+	for name, c := range tc {
+		*c = lipgloss.AdaptiveColor{
+			Light: string((*lc[name]).(lipgloss.Color)),
+			Dark:  string((*dc[name]).(lipgloss.Color)),
+		}
+	}
+	return t
+}
+
+// colors returns the colors of the theme indexed by the name used in the
+// theme section of the config file.
+func (t *theme) colors() map[string]*lipgloss.TerminalColor {
+	return map[string]*lipgloss.TerminalColor{
+		"text":     &t.Text,
+		"subtle":   &t.Subtle,
+		"item":     &t.Item,
+		"selected": &t.Selected,
+		"active":   &t.Active,
+		"dim":      &t.Dim,
+		"error":    &t.Error,
+		"border":   &t.Border,
+		"status":   &t.Status,
+		"add":      &t.Add,
+		"rename":   &t.Rename,
+		"kill":     &t.Kill,
+	}
+}
+
+// newTheme returns the built-in theme named in cfg with its colors overridden
+// by the configured ones. If NO_COLOR is set, every color is disabled.
+// Returns an error if the theme or a color name is unknown, or a color is malformed.
+func newTheme(cfg config.Theme) (theme, error) {
+	name := cfg.Name
+	if name == "" {
+		name = defaultTheme
+	}
+	t, ok := themes[name]
+	if !ok {
+		names := make([]string, 0, len(themes))
+		for n := range themes {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return theme{}, fmt.Errorf("unknown theme %q, available themes are %v", name, names)
+	}
+
+	colors := t.colors()
+	for colorName, value := range cfg.Colors {
+		c, ok := colors[colorName]
+		if !ok {
+			return theme{}, fmt.Errorf("unknown theme color %q", colorName)
+		}
+		if !validColor(value) {
+			return theme{}, fmt.Errorf("invalid value %q for theme color %q, expected #RRGGBB or an ANSI color number", value, colorName)
+		}
+		*c = lipgloss.Color(value)
+	}
+
+	if noColor {
+		for _, c := range colors {
+			*c = lipgloss.NoColor{}
+		}
+	}
+	return t, nil
+}
+
+// validColor reports whether value is a hex color or an ANSI color number (0-255).
+func validColor(value string) bool {
+	if hexColor.MatchString(value) {
+		return true
+	}
+	n, err := strconv.Atoi(value)
+	return err == nil && n >= 0 && n <= 255
+}
+
+// apply builds the styles used across the TUI from the theme colors.
+func (t theme) apply() {
+	subtle = t.Subtle
+	statusColor = t.Status
+	addColor = t.Add
+	renameColor = t.Rename
+	killColor = t.Kill
+	errorColor = t.Error
+
+	// list
+	itemStyle = lipgloss.NewStyle().PaddingLeft(4).Foreground(t.Item)
+	selectedItemStyle = lipgloss.NewStyle().PaddingLeft(2).Foreground(t.Selected).Bold(true)
+	activeSessionStyle = lipgloss.NewStyle().Bold(true).Foreground(t.Active)
+	activeSessionHelpStyle = lipgloss.NewStyle().Foreground(t.Dim).Render
+	errorStyle = lipgloss.NewStyle().Foreground(t.Error).Render
+	groupStyle = lipgloss.NewStyle().PaddingLeft(2).Bold(true).Foreground(t.Subtle)
+
+	// view
+	inputStyle = lipgloss.NewStyle()
+	base = lipgloss.NewStyle().Foreground(t.Text)
+	versionStyle = lipgloss.NewStyle().Foreground(t.Subtle).Render
+	titleStyle = lipgloss.NewStyle().Italic(true).Bold(true).Foreground(t.Text)
+	bulletDivider = lipgloss.NewStyle().
+		SetString(icons.Bullet).
+		Padding(0, 1).
+		Foreground(t.Subtle).
+		String()
+	dividerStyle = lipgloss.NewStyle().Foreground(t.Subtle)
+	borderStyle = lipgloss.NewStyle().Border(lipgloss.NormalBorder()).BorderForeground(t.Border)
+	inputHelpStyle = lipgloss.NewStyle().Foreground(t.Subtle)
+	dialogBoxStyle = lipgloss.NewStyle().
+		Padding(1, 2, 1).
+		Border(lipgloss.NormalBorder())
+
+	// preview
+	previewStyle = lipgloss.NewStyle().Border(lipgloss.NormalBorder()).BorderForeground(t.Border)
+	schematicWinStyle = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(t.Subtle)
+	schematicPaneStyle = lipgloss.NewStyle().Border(lipgloss.NormalBorder()).BorderForeground(t.Subtle).Foreground(t.Subtle)
+}
+
+func init() {
+	// Make the styles usable before a Model applies the configured theme.
+	themes[defaultTheme].apply()
+}
@@ -0,0 +1,143 @@
+// Package tui contains terminal user interface commands and related functionality.
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/phanorcoll/muxie/internal/tmux"
+)
+
+// windowItem represents a window of an expanded session in the TUI.
+// sessionName: the name of the session the window belongs to.
+// window: the tmux window data, including its panes.
+// last: true if this is the last window of the session, used to draw the tree.
+type windowItem struct {
+	sessionName     string
+	window          tmux.WindowData
+	last            bool
+	addSpacingUnder bool
+}
+
+func (i windowItem) FilterValue() string { return i.sessionName + " " + i.window.Name }
+
+// paneItem represents a pane of a window with more than one pane in the TUI.
+// sessionName: the name of the session the pane belongs to.
+// windowIndex: the index of the window the pane belongs to.
+// pane: the tmux pane data.
+// lastWindow, last: whether the parent window and the pane are the last of
+// their siblings, used to draw the tree.
+type paneItem struct {
+	sessionName     string
+	windowIndex     int
+	pane            tmux.PaneData
+	lastWindow      bool
+	last            bool
+	addSpacingUnder bool
+}
+
+func (i paneItem) FilterValue() string { return i.sessionName + " " + i.pane.Command }
+
+// expandSessions returns the items to display in the list, inserting the windows
+// and panes of every running session marked as expanded right after it.
+func expandSessions(sessions []list.Item, expanded map[string]bool) []list.Item {
+	var items []list.Item
+	for _, item := range sessions {
+		s, ok := item.(session)
+		if !ok || !s.isRunning || !expanded[s.sessionName] || len(s.windows) == 0 {
+			items = append(items, item)
+			continue
+		}
+
+		// Move the spacing under the last child of the session so the
+		// separation with config sessions stays in place.
+		spacing := s.addSpacingUnder
+		s.addSpacingUnder = false
+		items = append(items, s)
+
+		for wi, w := range s.windows {
+			lastWindow := wi == len(s.windows)-1
+			items = append(items, windowItem{
+				sessionName: s.sessionName,
+				window:      w,
+				last:        lastWindow,
+			})
+			if len(w.Panes) < 2 {
+				continue
+			}
+			for pi, p := range w.Panes {
+				items = append(items, paneItem{
+					sessionName: s.sessionName,
+					windowIndex: w.Index,
+					pane:        p,
+					lastWindow:  lastWindow,
+					last:        pi == len(w.Panes)-1,
+				})
+			}
+		}
+
+		if spacing {
+			switch last := items[len(items)-1].(type) {
+			case windowItem:
+				last.addSpacingUnder = true
+				items[len(items)-1] = last
+			case paneItem:
+				last.addSpacingUnder = true
+				items[len(items)-1] = last
+			}
+		}
+	}
+	return items
+}
+
+// renderWindow returns the line displayed for a window item.
+func renderWindow(i windowItem) string {
+	branch := icons.TreeBranch
+	if i.last {
+		branch = icons.TreeLast
+	}
+	name := fmt.Sprintf("%d: %s", i.window.Index, i.window.Name)
+	if i.window.Active {
+		name = activeSessionStyle.Render(name)
+	}
+	desc := fmt.Sprintf("  %s %s", branch, name)
+	if len(i.window.Panes) < 2 {
+		p := i.window.ActivePane()
+		desc += activeSessionHelpStyle(fmt.Sprintf("  %s %s", p.Command, shortenPath(p.Path)))
+	} else {
+		desc += activeSessionHelpStyle(fmt.Sprintf("  %d panes", len(i.window.Panes)))
+	}
+	return desc
+}
+
+// renderPane returns the line displayed for a pane item.
+func renderPane(i paneItem) string {
+	trunk := icons.TreePipe
+	if i.lastWindow {
+		trunk = "  "
+	}
+	branch := icons.TreeBranch
+	if i.last {
+		branch = icons.TreeLast
+	}
+	name := fmt.Sprintf("%d: %s", i.pane.Index, i.pane.Command)
+	if i.pane.Active {
+		name = activeSessionStyle.Render(name)
+	}
+	return fmt.Sprintf("  %s  %s %s", trunk, branch, name) +
+		activeSessionHelpStyle("  "+shortenPath(i.pane.Path))
+}
+
+// shortenPath replaces the user's home directory prefix in path with "~".
+func shortenPath(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return path
+	}
+	if path == home || strings.HasPrefix(path, home+string(os.PathSeparator)) {
+		return "~" + path[len(home):]
+	}
+	return path
+}
@@ -2,11 +2,18 @@
 package tui
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/phanorcoll/muxie/internal/config"
+	"github.com/phanorcoll/muxie/internal/project"
+	"github.com/phanorcoll/muxie/internal/state"
 	"github.com/phanorcoll/muxie/internal/tmux"
 )
 
@@ -15,83 +22,220 @@ import (
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	var cmds []tea.Cmd
+	prevIndex := m.sessionList.Index()
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch {
+		case m.failure != nil:
+			// The error panel takes every key until it is dismissed.
+			switch {
+			case key.Matches(msg, m.keys.Enter) && m.failure.retry != nil:
+				retry := m.failure.retry
+				m.failure = nil
+				return m, retry
+			case key.Matches(msg, m.keys.Escape):
+				m.failure = nil
+			case key.Matches(msg, m.keys.Quit):
+				return m, tea.Quit
+			}
+			return m, nil
+		case m.snapshots != nil || m.restore != nil:
+			if m.sessionList.FilterState() == list.Filtering {
+				break
+			}
+			switch {
+			case key.Matches(msg, m.keys.Enter):
+				if i, ok := m.sessionList.SelectedItem().(snapshotItem); ok {
+					return m, m.openRestore(i.snapshot)
+				}
+				return m, m.restoreCmd()
+			case key.Matches(msg, m.keys.Escape):
+				return m, m.backFromRestore()
+			case key.Matches(msg, m.keys.Restore):
+				return m, m.closeRestore()
+			case key.Matches(msg, m.keys.Select):
+				if i, ok := m.sessionList.SelectedItem().(savedItem); ok {
+					if m.selected[i.session.Name] {
+						delete(m.selected, i.session.Name)
+					} else {
+						m.selected[i.session.Name] = true
+					}
+					m.sessionList.CursorDown()
+				}
+				return m, nil
+			case key.Matches(msg, m.keys.Preview):
+				m.showPreview = !m.showPreview
+				m.previewKey = ""
+				m.resize()
+				return m, m.updatePreview()
+			case key.Matches(msg, m.keys.Help):
+				m.help.ShowAll = !m.help.ShowAll
+				m.resize()
+			case key.Matches(msg, m.keys.Quit):
+				return m, tea.Quit
+			}
 		case m.showInput:
 			switch {
+			case m.statusData.action == "a" && key.Matches(msg, pickerUp):
+				m.picker.move(-1, m.pickerRows())
+				return m, nil
+			case m.statusData.action == "a" && key.Matches(msg, pickerDown):
+				m.picker.move(1, m.pickerRows())
+				return m, nil
+			case m.statusData.action == "S" && !key.Matches(msg, m.keys.Escape):
+				choice := msg.String()
+				if key.Matches(msg, m.keys.Enter) {
+					choice = runningSwitch
+				}
+				cmd := m.startRunningCmd(m.targets[0], choice)
+				if cmd == nil {
+					return m, nil
+				}
+				m.closeInput()
+				return m, cmd
+			case m.statusData.action == "n" && !key.Matches(msg, m.keys.Escape):
+				choice := msg.String()
+				if key.Matches(msg, m.keys.Enter) {
+					choice = "enter"
+				}
+				cmd := newWindowCmd(m.pendingWindow, choice)
+				if cmd == nil {
+					return m, nil
+				}
+				// Show the new window once the sessions are refreshed.
+				m.expanded[m.pendingWindow.session] = true
+				m.closeInput()
+				return m, cmd
 			case key.Matches(msg, m.keys.Enter):
 				if m.statusData.action == "a" && m.showInput {
-					newName := m.sessionInput.Value()
+					newName, dir := m.picker.choice(m.pickerRows())
+					running := m.isRunning(newName)
+					m.closeInput()
 					if newName == "" || newName == m.activeSession {
-						m.sessionInput.Blur()
-						m.sessionInput.Reset()
-						m.showInput = false
-						m.statusData.action = ""
-						m.statusData.actionTitle = ""
-						m.statusData.icon = ""
-						m.statusData.color = "#FFF7DB"
 						return m, nil
 					}
-					err := tmux.CreateSession(newName, "")
-					if err != nil {
-						m.logger.Printf("Error creating session %s: %v", newName, err)
-					}
-					return m, tea.Quit
+					return m, actionCmd("create", "created", []string{newName}, true, func(name string) error {
+						// Picking the directory of a running session switches to it.
+						var err error
+						if running {
+							err = tmux.SwitchSession(name)
+						} else {
+							err = tmux.CreateSession(name, dir)
+						}
+						if err != nil {
+							return err
+						}
+						m.recordVisit(name)
+						return nil
+					})
 				}
 				if m.statusData.action == "d" && m.showInput {
-					option := m.sessionInput.Value()
-					if option == "y" {
-						si := m.sessionList.SelectedItem().(session)
-						err := tmux.KillSession(si.sessionName)
+					option, targets := m.sessionInput.Value(), m.targets
+					m.closeInput()
+					if option != "y" {
+						return m, nil
+					}
+					clear(m.selected)
+					return m, actionCmd("kill", "killed", targets, false, tmux.KillSession)
+				}
+				if m.statusData.action == "s" && m.showInput {
+					option, targets := m.sessionInput.Value(), m.targets
+					m.closeInput()
+					if option != "y" {
+						return m, nil
+					}
+					clear(m.selected)
+					return m, actionCmd("start", "started", targets, false, func(name string) error {
+						if err := m.startSession(name); err != nil {
+							return err
+						}
+						m.recordVisit(name)
+						return nil
+					})
+				}
+				if m.statusData.action == "w" && m.showInput {
+					parent, branch := m.targets[0], m.sessionInput.Value()
+					m.closeInput()
+					if branch == "" {
+						return m, nil
+					}
+					return m, worktreeCmd(actionCmd("create a worktree of", "created", []string{parent}, true, func(parent string) error {
+						name, err := m.addWorktree(parent, branch)
 						if err != nil {
-							m.logger.Printf("Error killing session %s: %v", si.sessionName, err)
+							return err
 						}
-						if !si.isFromConfig {
-							index := m.sessionList.GlobalIndex()
-							m.sessionList.RemoveItem(index)
+						if err := tmux.SwitchSession(name); err != nil {
+							return err
 						}
+						m.recordVisit(name)
+						return nil
+					}))
+				}
+				if m.statusData.action == "dw" && m.showInput {
+					option, t := m.sessionInput.Value(), m.pendingWindow
+					m.closeInput()
+					if option != "y" {
+						return m, nil
+					}
+					return m, killWindowCmd(t)
+				}
+				if m.statusData.action == "rw" && m.showInput {
+					t, newName := m.pendingWindow, m.sessionInput.Value()
+					m.closeInput()
+					if newName == "" {
+						return m, nil
+					}
+					return m, actionCmd("rename", "renamed", []string{t.label()}, false, func(string) error {
+						return tmux.RenameWindow(t.session, t.index, newName)
+					})
+				}
+				if m.statusData.action == "mw" && m.showInput {
+					t, dst := m.pendingWindow, m.sessionInput.Value()
+					m.closeInput()
+					if dst == "" {
+						return m, nil
 					}
-					m.sessionInput.Blur()
-					m.sessionInput.Reset()
-					m.showInput = false
-					m.statusData.action = ""
-					m.statusData.actionTitle = ""
-					m.statusData.icon = ""
-					m.statusData.color = "#FFF7DB"
-					return m, getSessionsCmd(m.config)
+					return m, actionCmd("move", "moved", []string{t.label()}, false, func(string) error {
+						return tmux.MoveWindow(t.session, t.index, dst)
+					})
+				}
+				if m.statusData.action == "xw" && m.showInput {
+					t, other := m.pendingWindow, m.sessionInput.Value()
+					m.closeInput()
+					if other == "" {
+						return m, nil
+					}
+					return m, actionCmd("swap", "swapped", []string{t.label()}, false, func(string) error {
+						return tmux.SwapWindows(t.session, t.index, other)
+					})
+				}
+				if m.statusData.action == "c" && m.showInput {
+					t, dir := m.pendingCapture, m.sessionInput.Value()
+					m.closeInput()
+					if dir == "" {
+						dir = t.dir
+					}
+					return m, captureCmd(t, dir)
 				}
 				if m.statusData.action == "r" && m.showInput {
-					si := m.sessionList.SelectedItem().(session)
-					newName := m.sessionInput.Value()
-					if newName != "" {
-						index := m.sessionList.GlobalIndex()
-						m.sessionList.SetItem(index, session{
-							sessionName: newName,
-						})
-						err := tmux.RenameSession(si.sessionName, newName)
-						if err != nil {
-							m.logger.Printf("Error renaming session to %s: %v", newName, err)
-						}
+					oldName, newName := m.targets[0], m.sessionInput.Value()
+					m.closeInput()
+					if newName == "" {
+						return m, nil
 					}
-					m.sessionInput.Blur()
-					m.sessionInput.Reset()
-					m.showInput = false
-					m.statusData.action = ""
-					m.statusData.actionTitle = ""
-					m.statusData.icon = ""
-					m.statusData.color = "#FFF7DB"
-					return m, getSessionsCmd(m.config)
+					return m, actionCmd("rename", "renamed", []string{oldName}, false, func(oldName string) error {
+						if err := tmux.RenameSession(oldName, newName); err != nil {
+							return err
+						}
+						if err := state.RenameVisits(oldName, newName); err != nil {
+							m.logger.Warn("could not rename session in history", "session", oldName, "err", err)
+						}
+						return nil
+					})
 				}
 
 			case key.Matches(msg, m.keys.Escape):
-				m.sessionInput.Blur()
-				m.sessionInput.Reset()
-				m.showInput = false
-				m.statusData.action = ""
-				m.statusData.actionTitle = ""
-				m.statusData.icon = ""
-				m.statusData.color = "#FFF7DB"
+				m.closeInput()
 				return m, nil
 			}
 		default:
@@ -104,74 +248,615 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.sessionInput.Placeholder = "type"
 				m.sessionInput.Focus()
 				m.statusData.actionTitle = "Name new session"
+				cmd = pickerCmd(m.config.Picker)
+				if cmd != nil {
+					m.statusData.actionTitle = "Pick a directory or name new session"
+				}
 				m.statusData.action = "a"
-				m.statusData.icon = ""
-				m.statusData.color = "#37A1C5"
-				return m, nil
+				m.statusData.icon = icons.Add
+				m.statusData.color = addColor
+				m.picker = picker{}
+				return m, cmd
 			case key.Matches(msg, m.keys.Rename):
-				si := m.sessionList.SelectedItem().(session)
+				if t, ok := m.selectedWindow(); ok {
+					if t.paneID != "" {
+						return m, m.sessionList.NewStatusMessage(errorMessage("select a window"))
+					}
+					m.openWindowInput("rw", t, fmt.Sprintf("Rename window %s", t.label()), "name", icons.Rename)
+					return m, nil
+				}
+				si, ok := m.sessionList.SelectedItem().(session)
+				if !ok {
+					return m, m.sessionList.NewStatusMessage(errorMessage("select a session"))
+				}
 				if si.isFromConfig {
-					statusCmd := m.sessionList.NewStatusMessage(errorStyle("󰗼 rename in config file"))
+					statusCmd := m.sessionList.NewStatusMessage(errorMessage("rename in config file"))
 					return m, statusCmd
 				}
 				m.showInput = true
+				m.targets = []string{si.sessionName}
 				m.sessionInput.Placeholder = "type"
 				m.sessionInput.Focus()
 				m.statusData.actionTitle = fmt.Sprintf("Rename %s", si.sessionName)
 				m.statusData.action = "r"
-				m.statusData.icon = "󰑕"
-				m.statusData.color = "#BAC537"
+				m.statusData.icon = icons.Rename
+				m.statusData.color = renameColor
+				return m, nil
+			case key.Matches(msg, m.keys.Worktree):
+				si, ok := m.sessionList.SelectedItem().(session)
+				if !ok {
+					return m, m.sessionList.NewStatusMessage(errorMessage("select a session"))
+				}
+				parent, ok := m.worktreeOf[si.sessionName]
+				if !ok {
+					return m, m.sessionList.NewStatusMessage(errorMessage("worktrees not enabled"))
+				}
+				m.showInput = true
+				m.targets = []string{parent}
+				m.sessionInput.Placeholder = "branch"
+				m.sessionInput.Focus()
+				m.statusData.actionTitle = fmt.Sprintf("New worktree of %s", parent)
+				m.statusData.action = "w"
+				m.statusData.icon = icons.Add
+				m.statusData.color = addColor
 				return m, nil
 			case key.Matches(msg, m.keys.Kill):
-				si := m.sessionList.SelectedItem().(session)
+				if len(m.selected) > 0 {
+					running, skipped := m.selectedSessions(func(s session) bool { return s.isRunning })
+					if len(running) == 0 {
+						return m, m.sessionList.NewStatusMessage(errorMessage("no selected session is running"))
+					}
+					m.openConfirm("d", running, skipped, "kill", icons.Kill, killColor)
+					return m, nil
+				}
+				if t, ok := m.selectedWindow(); ok {
+					verb := "kill window"
+					if t.paneID != "" {
+						verb = "kill pane"
+					}
+					m.openConfirm("dw", []string{t.label()}, 0, verb, icons.Kill, killColor)
+					m.pendingWindow = t
+					return m, nil
+				}
+				si, ok := m.sessionList.SelectedItem().(session)
+				if !ok {
+					return m, m.sessionList.NewStatusMessage(errorMessage("select a session"))
+				}
 				if !si.isRunning {
-					statusCmd := m.sessionList.NewStatusMessage(errorStyle("󰗼 not running"))
+					statusCmd := m.sessionList.NewStatusMessage(errorMessage("not running"))
 					return m, statusCmd
 				}
-				m.showInput = true
-				m.sessionInput.Placeholder = "y/n"
-				m.sessionInput.Focus()
-				m.statusData.action = "d"
-				m.statusData.actionTitle = fmt.Sprintf("kill %s ?", si.sessionName)
-				m.statusData.icon = "󰗨"
-				m.statusData.color = "#C53770"
+				m.openConfirm("d", []string{si.sessionName}, 0, "kill", icons.Kill, killColor)
 				return m, nil
 			case key.Matches(msg, m.keys.Start):
-				si := m.sessionList.SelectedItem().(session)
-				for _, s := range m.config.Sessions {
-					if s.Name == si.sessionName {
-						err := tmux.StartSession(s.Name, s.Directory, s.Windows)
-						if err != nil {
-							m.logger.Printf("Error starting session %s: %v", s.Name, err)
-						}
-						return m, tea.Quit
+				if len(m.selected) > 0 {
+					startable, skipped := m.selectedSessions(func(s session) bool { return (s.isFromConfig || s.isProject) && !s.isRunning })
+					if len(startable) == 0 {
+						return m, m.sessionList.NewStatusMessage(errorMessage("no selected session to start"))
+					}
+					m.openConfirm("s", startable, skipped, "start", icons.Config, addColor)
+					return m, nil
+				}
+				si, ok := m.sessionList.SelectedItem().(session)
+				if !ok {
+					return m, m.sessionList.NewStatusMessage(errorMessage("select a session"))
+				}
+				if !si.isFromConfig && !si.isProject {
+					return m, m.sessionList.NewStatusMessage(errorMessage("not in config"))
+				}
+				if si.isRunning {
+					m.openRunning(si.sessionName)
+					return m, nil
+				}
+				return m, actionCmd("start", "started", []string{si.sessionName}, true, func(name string) error {
+					if err := m.startSession(name); err != nil {
+						return err
 					}
+					if err := tmux.SwitchSession(name); err != nil {
+						return err
+					}
+					m.recordVisit(name)
+					return nil
+				})
+			case key.Matches(msg, m.keys.Select):
+				si, ok := m.sessionList.SelectedItem().(session)
+				if !ok {
+					return m, nil
+				}
+				if m.selected[si.sessionName] {
+					delete(m.selected, si.sessionName)
+				} else {
+					m.selected[si.sessionName] = true
 				}
+				m.sessionList.CursorDown()
+				m.skipGroupHeader(prevIndex)
 				return m, nil
 			case key.Matches(msg, m.keys.Enter):
-				si := m.sessionList.SelectedItem().(session)
+				switch si := m.sessionList.SelectedItem().(type) {
+				case windowItem:
+					return m, m.switchToWindow(si.sessionName, si.window.Index, "")
+				case paneItem:
+					return m, m.switchToWindow(si.sessionName, si.windowIndex, si.pane.ID)
+				}
+				si, ok := m.sessionList.SelectedItem().(session)
+				if !ok {
+					return m, nil
+				}
 				if si.sessionName == m.activeSession || !si.isRunning {
-					statusCmd := m.sessionList.NewStatusMessage(errorStyle("󰗼 active or not running"))
+					statusCmd := m.sessionList.NewStatusMessage(errorMessage("active or not running"))
 					return m, statusCmd
 				}
-				if err := tmux.SwitchSession(si.sessionName); err != nil {
-					m.logger.Printf("Error switching to session %s: %v", si.sessionName, err)
+				return m, actionCmd("switch to", "switched to", []string{si.sessionName}, true, func(name string) error {
+					if err := tmux.SwitchSession(name); err != nil {
+						return err
+					}
+					m.recordVisit(name)
+					return nil
+				})
+			case key.Matches(msg, m.keys.Expand):
+				switch si := m.sessionList.SelectedItem().(type) {
+				case session:
+					if !si.isRunning {
+						return m, m.sessionList.NewStatusMessage(errorMessage("not running"))
+					}
+					m.expanded[si.sessionName] = !m.expanded[si.sessionName]
+				case windowItem:
+					m.expanded[si.sessionName] = false
+					m.selectItem(si.sessionName)
+				case paneItem:
+					m.expanded[si.sessionName] = false
+					m.selectItem(si.sessionName)
 				}
-				return m, tea.Quit
+				return m, m.setItems()
+			case key.Matches(msg, m.keys.Sort):
+				m.sortMode = nextMode(sortModes, m.sortMode)
+				m.setTitle()
+				m.saveUI()
+				return m, m.setItems()
+			case key.Matches(msg, m.keys.Group):
+				m.groupMode = nextMode(groupModes, m.groupMode)
+				m.setTitle()
+				m.saveUI()
+				return m, m.setItems()
+			case key.Matches(msg, m.keys.Preview):
+				m.showPreview = !m.showPreview
+				m.previewKey = ""
+				m.resize()
+				return m, m.updatePreview()
+			case key.Matches(msg, m.keys.Restore):
+				return m, loadSnapshotsCmd()
+			case key.Matches(msg, m.keys.Capture):
+				return m, m.openCapture()
+			case key.Matches(msg, m.keys.Window):
+				return m, m.openNewWindow()
+			case key.Matches(msg, m.keys.Move), key.Matches(msg, m.keys.Swap):
+				t, ok := m.selectedWindow()
+				if !ok || t.paneID != "" {
+					return m, m.sessionList.NewStatusMessage(errorMessage("select a window"))
+				}
+				if key.Matches(msg, m.keys.Move) {
+					m.openWindowInput("mw", t, fmt.Sprintf("Move window %s to", t.label()), "session", icons.Rename)
+				} else {
+					m.openWindowInput("xw", t, fmt.Sprintf("Swap window %s with", t.label()), "index or name", icons.Rename)
+				}
+				return m, nil
 			case key.Matches(msg, m.keys.Help):
 				m.help.ShowAll = !m.help.ShowAll
-			case key.Matches(msg, defaultKeyMap.Quit):
+				m.resize()
+			case key.Matches(msg, m.keys.Quit):
 				return m, tea.Quit
 			}
 		}
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.resize()
+		// The preview has to be redrawn to fit the new size.
+		m.previewKey = ""
+		return m, m.updatePreview()
+	case refreshTickMsg:
+		// Force the preview to be captured again, as the content of the
+		// selected pane may have changed since the last tick.
+		m.previewKey = ""
+		return m, tea.Batch(getSessionsCmd(m.configSessions, m.logger), refreshCmd(m.config.Refresh()), m.updatePreview())
+	case projectsMsg:
+		if msg.err != nil {
+			m.logger.Warn("could not discover projects", "err", msg.err)
+		}
+		if msg.err == nil || msg.dirs != nil {
+			m.projects = msg.dirs
+			// Projects are merged with the sessions once they are retrieved.
+			if m.sessions != nil {
+				cmds = append(cmds, m.setItems())
+			}
+		}
+		if msg.stale {
+			cmds = append(cmds, scanProjectsCmd(m.config))
+		}
+		return m, tea.Batch(cmds...)
+	case pickerMsg:
+		if msg.err != nil {
+			m.logger.Warn("could not list directories", "err", msg.err)
+		}
+		if m.statusData.action == "a" {
+			m.picker.dirs = msg.dirs
+			m.picker.filter(m.sessionInput.Value())
+		}
+		return m, nil
+	case snapshotsMsg:
+		if msg.err != nil {
+			m.logger.Error("could not load snapshots", "err", msg.err)
+			m.failure = &actionMsg{verb: "load", failed: []string{"snapshots"}, err: msg.err, retry: loadSnapshotsCmd()}
+			return m, nil
+		}
+		return m, m.openSnapshots(msg.snapshots)
+	case previewMsg:
+		if msg.key == itemKey(m.sessionList.SelectedItem()) {
+			m.preview = msg
+		}
+		return m, nil
+	case actionMsg:
+		if msg.err != nil {
+			m.logger.Error("could not "+msg.verb, "targets", msg.failed, "err", msg.err)
+			m.failure = &msg
+		} else if msg.quit {
+			return m, tea.Quit
+		}
+		if msg.status != "" {
+			cmds = append(cmds, m.sessionList.NewStatusMessage(msg.status))
+		}
+		if msg.worktrees {
+			cmds = append(cmds, expandWorktreesCmd(m.config))
+		} else {
+			cmds = append(cmds, getSessionsCmd(m.configSessions, m.logger))
+		}
+		return m, tea.Batch(cmds...)
+	case worktreesMsg:
+		if msg.err != nil {
+			m.logger.Warn("could not list worktrees", "err", msg.err)
+		}
+		// Sessions with worktrees enabled are listed once per worktree.
+		m.configSessions = msg.sessions
+		m.worktreeOf = msg.parents
+		return m, getSessionsCmd(m.configSessions, m.logger)
 	case sessionsResponseMsg:
+		if msg.Err != nil {
+			m.logger.Error("could not refresh sessions", "err", msg.Err)
+			// Later refreshes keep showing the sessions last listed.
+			if m.failure == nil && m.sessions == nil {
+				m.failure = &actionMsg{
+					verb:   "list",
+					failed: []string{"sessions"},
+					err:    msg.Err,
+					retry:  getSessionsCmd(m.configSessions, m.logger),
+				}
+			}
+			return m, nil
+		}
 		m.activeSession = msg.ActiveSession
-		m.sessionList.SetItems(msg.SessionsList)
+		m.sessions = msg.SessionsList
+		cmds = append(cmds, m.setItems())
+	}
+	// While a dialog or the error panel is open, keys go to it only, so
+	// typing a name does not move the cursor of the list.
+	if _, isKey := msg.(tea.KeyMsg); !isKey || (!m.showInput && m.failure == nil) {
+		m.sessionList, cmd = m.sessionList.Update(msg)
+		cmds = append(cmds, cmd)
+		m.skipGroupHeader(prevIndex)
+	}
+	if _, ok := msg.(list.FilterMatchesMsg); ok && m.pendingSelect != "" {
+		m.selectItem(m.pendingSelect)
+		m.pendingSelect = ""
 	}
-	m.sessionList, cmd = m.sessionList.Update(msg)
-	cmds = append(cmds, cmd)
 	m.sessionInput, cmd = m.sessionInput.Update(msg)
 	cmds = append(cmds, cmd)
+	if m.statusData.action == "a" && m.sessionInput.Value() != m.picker.query {
+		m.picker.filter(m.sessionInput.Value())
+	}
+	cmds = append(cmds, m.updatePreview())
 
 	return m, tea.Batch(cmds...)
 }
+
+// selectItem moves the cursor to the visible item identified by key, as
+// returned by itemKey. The cursor is left untouched if no item matches.
+func (m *Model) selectItem(key string) {
+	if key == "" {
+		return
+	}
+	for i, item := range m.sessionList.VisibleItems() {
+		if itemKey(item) == key {
+			m.sessionList.Select(i)
+			return
+		}
+	}
+}
+
+// setItems arranges the current sessions with the sort and group modes,
+// expands them into list items and sets them on the list, keeping the
+// cursor on the previously selected item.
+func (m *Model) setItems() tea.Cmd {
+	var items []list.Item
+	if m.snapshots != nil || m.restore != nil {
+		items = m.restoreItems()
+	} else {
+		sessions := withProjects(m.sessions, m.projects, m.activeSession)
+		sessions = arrangeSessions(sessions, m.activeSession, m.sortMode, m.groupMode, m.history)
+		items = expandSessions(sessions, m.expanded)
+	}
+	if reflect.DeepEqual(m.sessionList.Items(), items) {
+		return nil
+	}
+	selected := itemKey(m.sessionList.SelectedItem())
+	if selected == "" && m.sortMode == "frecency" {
+		// On the first load, start on the session most likely to be
+		// switched to rather than on the active one.
+		selected = m.firstInactiveSession(items)
+	}
+	cmd := m.sessionList.SetItems(items)
+	if m.sessionList.FilterState() == list.Unfiltered {
+		m.selectItem(selected)
+		m.skipGroupHeader(-1)
+	} else {
+		// The list refilters asynchronously, so the cursor can only be
+		// restored once the FilterMatchesMsg has been handled.
+		m.pendingSelect = selected
+	}
+	return cmd
+}
+
+// firstInactiveSession returns the key of the first running session in items
+// other than the active one, or an empty string if there is none.
+func (m Model) firstInactiveSession(items []list.Item) string {
+	for _, item := range items {
+		if s, ok := item.(session); ok && s.isRunning && s.sessionName != m.activeSession {
+			return itemKey(s)
+		}
+	}
+	return ""
+}
+
+// isRunning reports whether a session with the given name is running.
+func (m Model) isRunning(name string) bool {
+	for _, item := range m.sessions {
+		if s, ok := item.(session); ok && s.sessionName == name {
+			return s.isRunning
+		}
+	}
+	return false
+}
+
+// recordVisit records a switch to or start of the named session in the
+// usage history.
+func (m Model) recordVisit(name string) {
+	if err := state.RecordVisit(name); err != nil {
+		m.logger.Warn("could not record visit", "session", name, "err", err)
+	}
+}
+
+// switchToWindow returns a command switching the client to the given session,
+// then selecting the window with the given index and, if paneID is not empty,
+// that pane.
+func (m Model) switchToWindow(sessionName string, windowIndex int, paneID string) tea.Cmd {
+	target := fmt.Sprintf("%s:%d", sessionName, windowIndex)
+	return actionCmd("switch to", "switched to", []string{target}, true, func(string) error {
+		if err := tmux.SwitchSession(sessionName); err != nil {
+			return err
+		}
+		m.recordVisit(sessionName)
+		if err := tmux.SelectWindow(sessionName, windowIndex); err != nil {
+			return err
+		}
+		if paneID != "" {
+			return tmux.SelectPane(paneID)
+		}
+		return nil
+	})
+}
+
+// updatePreview requests a new preview if the selected item changed since the
+// last request. Returns nil if the preview is hidden or up to date.
+func (m *Model) updatePreview() tea.Cmd {
+	if m.layout().previewWidth == 0 {
+		return nil
+	}
+	item := m.sessionList.SelectedItem()
+	key := itemKey(item)
+	if key == m.previewKey {
+		return nil
+	}
+	m.previewKey = key
+	if item == nil {
+		m.preview = previewMsg{}
+		return nil
+	}
+	return previewCmd(m.config, m.configSessions, item, m.layout().previewWidth)
+}
+
+// errNotStartable is returned when starting a session that is neither
+// defined in the config file nor found in the project roots.
+var errNotStartable = errors.New("session not found in config or project roots")
+
+// startSession starts the session with the given name from the config file
+// or the project roots, without switching to it.
+func (m Model) startSession(name string) error {
+	s, err := m.startableSession(name)
+	if err != nil {
+		return err
+	}
+	return tmux.StartSession(s, m.config.KeepFailedSessions)
+}
+
+// startableSession returns the definition of the session with the given
+// name from the config file or the project roots.
+func (m Model) startableSession(name string) (config.Session, error) {
+	return findSession(m.config, m.configSessions, m.projects, name)
+}
+
+// findSession returns the definition of the session with the given name
+// among the config sessions, with worktree sessions expanded, and the
+// repositories in projects.
+func findSession(cfg *config.Config, sessions []config.Session, projects []string, name string) (config.Session, error) {
+	for _, s := range sessions {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	for _, dir := range projects {
+		if project.SessionName(dir) == name {
+			// Sessions are named after the directory, even if its
+			// .muxie.yml file names them otherwise.
+			s, err := projectSession(cfg, dir)
+			s.Name = name
+			return s, err
+		}
+	}
+	return config.Session{}, errNotStartable
+}
+
+// FindSession returns the definition of the session with the given name
+// that the TUI would start: a config session, a session of a worktree of a
+// config session, or a repository found in the project roots. Repositories
+// are read from the cache the TUI keeps, and the project roots are scanned
+// only if it is missing or outdated.
+func FindSession(cfg *config.Config, name string) (config.Session, error) {
+	// Sessions whose worktrees cannot be listed are kept as they are.
+	sessions, _, _ := worktreeSessions(cfg.Sessions)
+	s, err := findSession(cfg, sessions, nil, name)
+	if err == nil || len(cfg.ProjectRoots) == 0 {
+		return s, err
+	}
+	p, err := state.LoadProjects()
+	dirs := p.Dirs
+	if err != nil || p.Key != projectsKey(cfg) {
+		dirs, err = project.Discover(cfg.ProjectRoots, cfg.ProjectDepth, cfg.ProjectIgnore)
+		if err != nil {
+			return config.Session{}, fmt.Errorf("could not scan project roots: %w", err)
+		}
+	}
+	return findSession(cfg, nil, dirs, name)
+}
+
+// Choices offered by the dialog opened when starting a running session.
+const (
+	runningSwitch    = "s"
+	runningReconcile = "r"
+	runningRestart   = "R"
+)
+
+// openRunning opens the dialog asking what to do with the running config
+// session with the given name: switch to it, reconcile it with the config
+// or restart it. The choice is made with a single key.
+func (m *Model) openRunning(name string) {
+	m.showInput = true
+	m.targets = []string{name}
+	m.sessionInput.Placeholder = runningSwitch + "/" + runningReconcile + "/" + runningRestart
+	m.sessionInput.Focus()
+	m.statusData.action = "S"
+	m.statusData.icon = icons.Config
+	m.statusData.color = addColor
+	m.statusData.actionTitle = fmt.Sprintf("%s is already running", name)
+	m.statusData.detail = strings.Join([]string{
+		runningSwitch + " - switch to it",
+		runningReconcile + " - add missing windows and panes",
+		runningRestart + " - restart it from the config",
+	}, "\n")
+}
+
+// startRunningCmd returns a command applying the choice made in the dialog
+// opened by openRunning to the running session with the given name, then
+// switching to it. Returns nil for keys that are not a choice.
+func (m Model) startRunningCmd(name, choice string) tea.Cmd {
+	var verb, past string
+	var op func(s config.Session) error
+	switch choice {
+	case runningSwitch:
+		verb, past = "switch to", "switched to"
+		op = func(config.Session) error { return nil }
+	case runningReconcile:
+		verb, past = "reconcile", "reconciled"
+		op = func(s config.Session) error {
+			return tmux.ReconcileSession(s)
+		}
+	case runningRestart:
+		verb, past = "restart", "restarted"
+		op = func(s config.Session) error {
+			return tmux.RestartSession(s, m.config.KeepFailedSessions)
+		}
+	default:
+		return nil
+	}
+	return actionCmd(verb, past, []string{name}, true, func(name string) error {
+		s, err := m.startableSession(name)
+		if err != nil {
+			return err
+		}
+		if err := op(s); err != nil {
+			return err
+		}
+		if err := tmux.SwitchSession(name); err != nil {
+			return err
+		}
+		m.recordVisit(name)
+		return nil
+	})
+}
+
+// selectedSessions returns the names of the selected sessions matching keep,
+// in list order, and how many selected sessions did not match. Selected
+// sessions hidden by the filter are left out, so actions only apply to what
+// is shown.
+func (m Model) selectedSessions(keep func(session) bool) ([]string, int) {
+	var names []string
+	skipped := 0
+	for _, item := range m.sessionList.VisibleItems() {
+		s, ok := item.(session)
+		if !ok || !m.selected[s.sessionName] {
+			continue
+		}
+		if keep(s) {
+			names = append(names, s.sessionName)
+		} else {
+			skipped++
+		}
+	}
+	return names, skipped
+}
+
+// openConfirm opens a y/n dialog asking to apply verb to the target sessions,
+// listing them and how many selected sessions are skipped.
+func (m *Model) openConfirm(action string, targets []string, skipped int, verb, icon string, color lipgloss.TerminalColor) {
+	m.showInput = true
+	m.targets = targets
+	m.sessionInput.Placeholder = "y/n"
+	m.sessionInput.Focus()
+	m.statusData.action = action
+	m.statusData.icon = icon
+	m.statusData.color = color
+	if len(targets) == 1 && skipped == 0 {
+		m.statusData.actionTitle = fmt.Sprintf("%s %s ?", verb, targets[0])
+		m.statusData.detail = ""
+		return
+	}
+	m.statusData.actionTitle = fmt.Sprintf("%s %d sessions ?", verb, len(targets))
+	m.statusData.detail = strings.Join(targets, ", ")
+	if skipped > 0 {
+		m.statusData.detail += fmt.Sprintf("\n(%d selected skipped)", skipped)
+	}
+}
+
+// closeInput hides the dialog and resets the status to idle.
+func (m *Model) closeInput() {
+	m.sessionInput.Blur()
+	m.sessionInput.Reset()
+	m.sessionInput.CharLimit = inputCharLimit
+	m.showInput = false
+	m.targets = nil
+	m.picker = picker{}
+	m.pendingCapture = captureTarget{}
+	m.pendingWindow = windowTarget{}
+	m.statusData.action = ""
+	m.statusData.actionTitle = ""
+	m.statusData.detail = ""
+	m.statusData.icon = icons.Idle
+	m.statusData.color = statusColor
+}
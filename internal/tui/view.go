@@ -2,83 +2,142 @@
 package tui
 
 import (
-	"os"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
-	"golang.org/x/term"
 )
 
-var (
-	widthTerm, heightTerm = func() (int, int) {
-		w, h, err := term.GetSize(int(os.Stdout.Fd()))
-		if err != nil {
-			return 80, 20
-		}
-		return w, h
-	}()
+const (
+	// minListWidth and minListHeight are the smallest size of the session
+	// list panel before switching to compact mode.
+	minListWidth  = 47
+	minListHeight = 13
+	// maxListWidth caps the width of the list panel when there is no
+	// preview next to it, so it stays readable in wide terminals.
+	maxListWidth = 80
+	// minPreviewWidth is the narrowest preview panel worth showing.
+	minPreviewWidth = 30
+)
 
-	width  = 47
-	height = 13
+// Colors and styles of the view, built from the theme by theme.apply.
+var (
 	// set of colors
-	normal  = lipgloss.Color("#EEEEEE")
-	subtle  = lipgloss.Color("#72726F")
-	special = lipgloss.AdaptiveColor{Light: "#43BF6D", Dark: "#73F59F"}
+	subtle      lipgloss.TerminalColor
+	statusColor lipgloss.TerminalColor
+	addColor    lipgloss.TerminalColor
+	renameColor lipgloss.TerminalColor
+	killColor   lipgloss.TerminalColor
+	errorColor  lipgloss.TerminalColor
 
 	// set of styles
-	inputStyle    = lipgloss.NewStyle()
-	base          = lipgloss.NewStyle().Foreground(normal)
-	url           = lipgloss.NewStyle().Foreground(special).Render
-	versionStyle  = lipgloss.NewStyle().Foreground(subtle).Render
-	titleStyle    = lipgloss.NewStyle().Italic(true).Bold(true).Foreground(normal)
-	bulletDivider = lipgloss.NewStyle().
-			SetString("•").
-			Padding(0, 1).
-			Foreground(subtle).
-			String()
-	divider = base.
-		BorderStyle(lipgloss.NormalBorder()).
-		BorderTop(true).
-		BorderForeground(subtle).
-		Width(width).
-		Render()
-	borderStyle    = lipgloss.NewStyle().Border(lipgloss.NormalBorder()).BorderForeground(lipgloss.Color("#5a5a5a"))
-	inputHelpStyle = lipgloss.NewStyle().Foreground(lipgloss.Color(subtle))
-
-	dialogBoxStyle = lipgloss.NewStyle().
-			Padding(1, 2, 1).
-			Border(lipgloss.NormalBorder())
+	inputStyle     lipgloss.Style
+	base           lipgloss.Style
+	versionStyle   func(...string) string
+	titleStyle     lipgloss.Style
+	bulletDivider  string
+	dividerStyle   lipgloss.Style
+	borderStyle    lipgloss.Style
+	inputHelpStyle lipgloss.Style
+	dialogBoxStyle lipgloss.Style
 )
 
+// layout describes the size of the panels making up the TUI.
+// width, height: the size of the content of the main panel.
+// previewWidth: the width of the preview panel, 0 if it is hidden.
+// compact: true if the TUI is rendered without borders, title or preview.
+type layout struct {
+	width        int
+	height       int
+	previewWidth int
+	compact      bool
+}
+
+// layout computes the size of every panel from the terminal size.
+func (m Model) layout() layout {
+	if m.config.Compact || m.width < minListWidth+2 || m.height < minListHeight+2 {
+		return layout{width: m.width, height: m.height, compact: true}
+	}
+
+	// Leave room for the borders of the main panel.
+	l := layout{width: m.width - 2, height: m.height - 2}
+	if m.showPreview && l.width >= minListWidth+minPreviewWidth+2 {
+		l.width = max(minListWidth, l.width*2/5)
+		l.previewWidth = m.width - 2 - l.width - 2
+	} else {
+		l.width = min(l.width, maxListWidth)
+	}
+	return l
+}
+
+// headerHeight returns the number of lines used by the header, including
+// the divider and empty line under the full header.
+func (l layout) headerHeight() int {
+	if l.compact {
+		return 1
+	}
+	return 3
+}
+
+// resize sets the size of the list and help to fill the current layout.
+func (m *Model) resize() {
+	l := m.layout()
+	m.help.Width = l.width
+	m.sessionList.SetShowTitle(!l.compact)
+	helpHeight := lipgloss.Height(m.help.View(m.keys))
+	// Reserve one line for the spacing rendered under the last running
+	// session, which the list does not account for.
+	m.sessionList.SetSize(l.width, max(1, l.height-l.headerHeight()-helpHeight-1))
+}
+
 // View renders the entire TUI based on the current state of the Model.
 // It constructs the header, content area (input dialog or session list), and applies styling.
 // The output is centered and bordered according to the terminal dimensions.
 func (m Model) View() string {
+	l := m.layout()
 	doc := strings.Builder{}
 	w := lipgloss.Width
 	muxiemeta := versionStyle(m.version)
 	// header
 	{
-		// versionInfo := versionStyle(m.version)
-		logo := titleStyle.Render("󱌖 Muxie")
-		logoversion := lipgloss.JoinHorizontal(lipgloss.Right, logo, " ", muxiemeta)
-		actionIcon := base.Foreground(lipgloss.Color(m.statusData.color)).Render(m.statusData.icon)
-		activeSesh := titleStyle.Width(width - w(logoversion)).Render(actionIcon + bulletDivider + m.activeSession)
-		row := lipgloss.JoinHorizontal(lipgloss.Center, activeSesh, logoversion)
-		header := lipgloss.JoinVertical(lipgloss.Top, row, divider)
-		doc.WriteString(header)
+		actionIcon := base.Foreground(m.statusData.color).Render(m.statusData.icon)
+		if l.compact {
+			doc.WriteString(titleStyle.Width(l.width).MaxWidth(l.width).Render(actionIcon+bulletDivider+m.activeSession) + "\n")
+		} else {
+			logo := titleStyle.Render(icons.Logo + " Muxie")
+			logoversion := lipgloss.JoinHorizontal(lipgloss.Right, logo, " ", muxiemeta)
+			activeSesh := titleStyle.Width(l.width - w(logoversion)).Render(actionIcon + bulletDivider + m.activeSession)
+			row := lipgloss.JoinHorizontal(lipgloss.Center, activeSesh, logoversion)
+			divider := dividerStyle.Render(strings.Repeat(lipgloss.NormalBorder().Top, l.width))
+			header := lipgloss.JoinVertical(lipgloss.Top, row, divider, "")
+			doc.WriteString(header + "\n")
+		}
 	}
 	// content
 	{
-		if m.showInput {
-			question := base.Foreground(lipgloss.Color(m.statusData.color)).Render(m.statusData.actionTitle)
-			input := inputStyle.Foreground(lipgloss.Color(m.statusData.color)).Render(m.sessionInput.View())
+		if m.failure != nil {
+			// Keep the panel inside the main panel, leaving room for its border and padding.
+			panel := lipgloss.Place(l.width, l.height-l.headerHeight(),
+				lipgloss.Center, lipgloss.Center,
+				dialogBoxStyle.BorderForeground(errorColor).Render(renderFailure(*m.failure, l.width-8)),
+			)
+			doc.WriteString(panel)
+		} else if m.showInput {
+			question := base.Foreground(m.statusData.color).Render(m.statusData.actionTitle)
+			if m.statusData.detail != "" {
+				// Keep the dialog inside the panel, leaving room for its border and padding.
+				detail := inputHelpStyle.Width(max(1, l.width-8)).Render(m.statusData.detail)
+				question = lipgloss.JoinVertical(lipgloss.Left, question, detail)
+			}
+			input := inputStyle.Foreground(m.statusData.color).Render(m.sessionInput.View())
+			if m.statusData.action == "a" && len(m.picker.dirs) > 0 {
+				input = lipgloss.JoinVertical(lipgloss.Left, input, m.picker.view(m.pickerRows(), max(1, l.width-8)))
+			}
 			inputHelpStyle := inputHelpStyle.Render("esc - cancel")
 			ui := lipgloss.JoinVertical(lipgloss.Left, question, input, inputHelpStyle)
 
-			dialog := lipgloss.Place(width, 9,
+			dialog := lipgloss.Place(l.width, l.height-l.headerHeight(),
 				lipgloss.Center, lipgloss.Center,
-				dialogBoxStyle.BorderForeground(lipgloss.Color(m.statusData.color)).Render(ui),
+				dialogBoxStyle.BorderForeground(m.statusData.color).Render(ui),
 			)
 			doc.WriteString(dialog)
 		} else {
@@ -87,8 +146,16 @@ func (m Model) View() string {
 		}
 	}
 	content := doc.String()
-	borderedContainer := borderStyle.Width(width).Height(height).Render(content)
+	if l.compact {
+		return lipgloss.NewStyle().MaxWidth(l.width).MaxHeight(l.height).Render(content)
+	}
+
+	borderedContainer := borderStyle.Width(l.width).Height(l.height).MaxHeight(l.height + 2).Render(content)
+	if l.previewWidth > 0 {
+		preview := renderPreview(m.preview, l.previewWidth, l.height)
+		borderedContainer = lipgloss.JoinHorizontal(lipgloss.Top, borderedContainer, preview)
+	}
 	return lipgloss.Place(
-		widthTerm, heightTerm, lipgloss.Center, lipgloss.Center, borderedContainer,
+		m.width, m.height, lipgloss.Center, lipgloss.Center, borderedContainer,
 	)
 }
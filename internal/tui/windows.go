@@ -0,0 +1,154 @@
+// Package tui contains terminal user interface commands and related functionality.
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/phanorcoll/muxie/internal/config"
+	"github.com/phanorcoll/muxie/internal/tmux"
+)
+
+// maxTemplates is the number of window templates offered by the new window
+// dialog, each chosen with a digit.
+const maxTemplates = 9
+
+// windowTarget is the window or pane the open window dialog applies to.
+// paneID, paneIndex: the pane, if the dialog applies to a pane.
+// dir: the working directory of the session, for new windows.
+// templates: the windows offered by the new window dialog.
+type windowTarget struct {
+	session   string
+	index     int
+	paneID    string
+	paneIndex int
+	dir       string
+	templates []config.Window
+}
+
+// label returns how the target is named in messages, e.g. "work:1" or "work:1.0".
+func (t windowTarget) label() string {
+	if t.paneID != "" {
+		return fmt.Sprintf("%s:%d.%d", t.session, t.index, t.paneIndex)
+	}
+	return fmt.Sprintf("%s:%d", t.session, t.index)
+}
+
+// selectedWindow returns the window or pane under the cursor. Returns false
+// if the cursor is on something else, e.g. a session.
+func (m Model) selectedWindow() (windowTarget, bool) {
+	switch i := m.sessionList.SelectedItem().(type) {
+	case windowItem:
+		return windowTarget{session: i.sessionName, index: i.window.Index}, true
+	case paneItem:
+		return windowTarget{session: i.sessionName, index: i.windowIndex, paneID: i.pane.ID, paneIndex: i.pane.Index}, true
+	}
+	return windowTarget{}, false
+}
+
+// openWindowInput opens a dialog asking for a name for the action on the
+// window t, e.g. the new name of the window.
+func (m *Model) openWindowInput(action string, t windowTarget, title, placeholder, icon string) {
+	m.pendingWindow = t
+	m.showInput = true
+	m.targets = []string{t.label()}
+	m.sessionInput.Placeholder = placeholder
+	m.sessionInput.Focus()
+	m.statusData.action = action
+	m.statusData.icon = icon
+	m.statusData.color = renameColor
+	m.statusData.actionTitle = title
+}
+
+// windowTemplates returns the windows that can be added to the running
+// session with the given name: the windows of its config, if any, then the
+// window templates of the config file.
+func (m Model) windowTemplates(name string) []config.Window {
+	var templates []config.Window
+	if s, err := m.startableSession(name); err == nil {
+		templates = append(templates, s.Windows...)
+	}
+	templates = append(templates, m.config.WindowTemplates...)
+	return templates[:min(len(templates), maxTemplates)]
+}
+
+// openNewWindow opens the dialog offering the window templates to add a
+// window to the session under the cursor, or to the session of the window
+// or pane under it. The choice is made with a single key.
+// Returns a status message instead if the session is not running.
+func (m *Model) openNewWindow() tea.Cmd {
+	var name string
+	switch i := m.sessionList.SelectedItem().(type) {
+	case session:
+		if !i.isRunning {
+			return m.sessionList.NewStatusMessage(errorMessage("not running"))
+		}
+		name = i.sessionName
+	case windowItem:
+		name = i.sessionName
+	case paneItem:
+		name = i.sessionName
+	default:
+		return m.sessionList.NewStatusMessage(errorMessage("select a session"))
+	}
+	t := windowTarget{session: name, templates: m.windowTemplates(name)}
+	for _, item := range m.sessions {
+		if s, ok := item.(session); ok && s.sessionName == name {
+			t.dir = s.path
+		}
+	}
+
+	lines := make([]string, 0, len(t.templates)+1)
+	for i, w := range t.templates {
+		commands := make([]string, 0, len(w.Panes))
+		for _, p := range w.Panes {
+			if p.Command != "" {
+				commands = append(commands, p.Command)
+			}
+		}
+		lines = append(lines, fmt.Sprintf("%d - %s %s", i+1, w.Name, inputHelpStyle.Render(strings.Join(commands, ", "))))
+	}
+	lines = append(lines, "enter - empty window")
+
+	m.pendingWindow = t
+	m.showInput = true
+	m.targets = []string{name}
+	m.sessionInput.Placeholder = "1-" + strconv.Itoa(max(1, len(t.templates)))
+	m.sessionInput.Focus()
+	m.statusData.action = "n"
+	m.statusData.icon = icons.Add
+	m.statusData.color = addColor
+	m.statusData.actionTitle = fmt.Sprintf("New window in %s", name)
+	m.statusData.detail = strings.Join(lines, "\n")
+	return nil
+}
+
+// newWindowCmd returns a command adding a window to the session of t from
+// the template chosen with the given key, "enter" for an empty window.
+// Returns nil for keys that are not a choice.
+func newWindowCmd(t windowTarget, choice string) tea.Cmd {
+	var w config.Window
+	if choice != "enter" {
+		n, err := strconv.Atoi(choice)
+		if err != nil || n < 1 || n > len(t.templates) {
+			return nil
+		}
+		w = t.templates[n-1]
+	}
+	return actionCmd("add a window to", "added a window to", []string{t.session}, false, func(name string) error {
+		_, err := tmux.NewWindowFromTemplate(name, t.dir, w)
+		return err
+	})
+}
+
+// killWindowCmd returns a command killing the window or pane t.
+func killWindowCmd(t windowTarget) tea.Cmd {
+	return actionCmd("kill", "killed", []string{t.label()}, false, func(string) error {
+		if t.paneID != "" {
+			return tmux.KillPane(t.paneID)
+		}
+		return tmux.KillWindow(t.session, t.index)
+	})
+}
@@ -0,0 +1,107 @@
+// Package tui contains terminal user interface commands and related functionality.
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/phanorcoll/muxie/internal/config"
+	"github.com/phanorcoll/muxie/internal/git"
+	"github.com/phanorcoll/muxie/internal/tmux"
+)
+
+// worktreeSessions returns the config sessions with every session that has
+// worktrees enabled replaced by one session per worktree of its repository.
+// parents maps the name of every worktree session to the name of the config
+// session it comes from. Sessions whose worktrees cannot be listed are kept
+// as they are, and the errors are returned along with the sessions.
+func worktreeSessions(sessions []config.Session) (expanded []config.Session, parents map[string]string, err error) {
+	parents = make(map[string]string)
+	var errs []error
+	for _, s := range sessions {
+		if !s.Worktrees {
+			expanded = append(expanded, s)
+			continue
+		}
+		worktrees, err := git.Worktrees(config.ExpandHomeDir(s.Directory))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("session %s: %w", s.Name, err))
+			expanded = append(expanded, s)
+			continue
+		}
+		for _, w := range worktrees {
+			if w.Bare {
+				continue
+			}
+			ws := inWorktree(s, w)
+			parents[ws.Name] = s.Name
+			expanded = append(expanded, ws)
+		}
+	}
+	return expanded, parents, errors.Join(errs...)
+}
+
+// worktreeSessionName returns the name of the session of worktree w of
+// the config session with the given name, e.g. muxie/main. Worktrees with
+// a detached HEAD are named after their directory.
+func worktreeSessionName(name string, w git.Worktree) string {
+	label := w.Branch
+	if label == "" {
+		label = filepath.Base(w.Path)
+	}
+	return tmux.SanitizeName(name + "/" + label)
+}
+
+// inWorktree returns a copy of the config session s rooted in worktree w.
+// Window and pane directories inside the repository are moved to the same
+// place in the worktree.
+func inWorktree(s config.Session, w git.Worktree) config.Session {
+	repo := filepath.Clean(config.ExpandHomeDir(s.Directory))
+	rebase := func(dir string) string {
+		rel, err := filepath.Rel(repo, filepath.Clean(config.ExpandHomeDir(dir)))
+		if dir == "" || err != nil || strings.HasPrefix(rel, "..") {
+			return dir
+		}
+		return filepath.Join(w.Path, rel)
+	}
+
+	s.Name = worktreeSessionName(s.Name, w)
+	s.Directory = w.Path
+	s.Worktrees = false
+	windows := make([]config.Window, len(s.Windows))
+	for i, win := range s.Windows {
+		win.Directory = rebase(win.Directory)
+		panes := make([]config.Pane, len(win.Panes))
+		for j, p := range win.Panes {
+			p.Directory = rebase(p.Directory)
+			panes[j] = p
+		}
+		win.Panes = panes
+		windows[i] = win
+	}
+	s.Windows = windows
+	return s
+}
+
+// addWorktree creates a worktree for branch in the repository of the config
+// session with the given name, then starts its session and returns the name
+// of the session.
+func (m Model) addWorktree(parent, branch string) (string, error) {
+	for _, s := range m.config.Sessions {
+		if s.Name != parent {
+			continue
+		}
+		path, err := git.AddWorktree(config.ExpandHomeDir(s.Directory), branch)
+		if err != nil {
+			return "", err
+		}
+		ws := inWorktree(s, git.Worktree{Path: path, Branch: branch})
+		if err := tmux.StartSession(ws, m.config.KeepFailedSessions); err != nil {
+			return "", err
+		}
+		return ws.Name, nil
+	}
+	return "", errNotStartable
+}